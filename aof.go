@@ -0,0 +1,432 @@
+package fastlocalcache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FsyncPolicy tells how often the append-only file is synced to disk.
+type FsyncPolicy int
+
+const (
+	// FsyncEverySecond syncs once a second, a crash loses at most the last
+	// second of writes.
+	FsyncEverySecond FsyncPolicy = iota
+	// FsyncAlways syncs after every write, the safest and the slowest.
+	FsyncAlways
+	// FsyncNever leaves syncing to the operating system, writes are still
+	// handed to it once a second.
+	FsyncNever
+)
+
+// The append-only file is the magic bytes and the format version followed by
+// records, each the uvarint length of its payload, the payload and the big
+// endian CRC32 (IEEE) of the payload. A payload is an operation followed by
+// an entry as in snapshots, for logCounter followed by the varint count too,
+// or by the key only for logDel.
+const (
+	logMagic   = "FLCA"
+	logVersion = 1
+
+	logSet     byte = 1
+	logCounter byte = 2
+	logDel     byte = 3
+
+	// logCompactMinSize is the size below which the log is not compacted, it
+	// is compacted when it doubled since the last compaction otherwise.
+	logCompactMinSize = 1 << 20
+	logFlushInterval  = time.Second
+)
+
+// appendLog records the writes of the shards, which append to it with their
+// lock held so that the records of a key are in the order of its writes.
+type appendLog struct {
+	mu            sync.Mutex
+	path          string
+	fsync         FsyncPolicy
+	file          *os.File
+	w             *bufio.Writer
+	size          int64         // bytes written to file
+	compactedSize int64         // size after the last compaction
+	rewrite       *bytes.Buffer // records appended during a compaction, nil otherwise
+	payload       bytes.Buffer  // scratch space for encoding records
+	err           error         // first write error, the log stops at it
+	closed        bool
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// openAppendLog opens or creates the log at path and replays it into c. A
+// torn or corrupt tail, left by a crash during a write, is cut off.
+func openAppendLog(c *Cache, path string, fsync FsyncPolicy) (*appendLog, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	size, err := c.replayLog(file)
+	if err == nil && size == 0 {
+		// at offset 0, replacing a torn header
+		_, err = file.WriteAt([]byte(logMagic+string([]byte{logVersion})), 0)
+		size = int64(len(logMagic) + 1)
+	}
+	if err == nil {
+		err = file.Truncate(size)
+	}
+	if err == nil {
+		_, err = file.Seek(size, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	l := &appendLog{
+		path:          path,
+		fsync:         fsync,
+		file:          file,
+		w:             bufio.NewWriter(file),
+		size:          size,
+		compactedSize: size,
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	for _, s := range c.shardedMap.shards {
+		s.log = l
+	}
+	go l.run(c)
+	return l, nil
+}
+
+// replayLog applies the records of the log to c and returns the offset after
+// the last valid one, 0 if the log is empty or its header was torn.
+func (c *Cache) replayLog(r io.Reader) (int64, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(logMagic)+1)
+	if n, err := io.ReadFull(br, header); err != nil {
+		// the log is empty, or a crash while creating it left part of the
+		// header; a short file that is not a log fails the magic bytes below
+		torn := err == io.ErrUnexpectedEOF && string(header[:n]) == (logMagic + string([]byte{logVersion}))[:n]
+		if err == io.EOF || torn {
+			return 0, nil
+		}
+		if err != io.ErrUnexpectedEOF {
+			return 0, fmt.Errorf("append-only file: %w", err)
+		}
+	}
+	if string(header[:len(logMagic)]) != logMagic {
+		return 0, errors.New("append-only file: bad magic bytes")
+	}
+	if version := header[len(logMagic)]; version != logVersion {
+		return 0, fmt.Errorf("append-only file: unsupported version %d", version)
+	}
+
+	offset := int64(len(header))
+	now := c.clock.Now()
+	for {
+		payload, n, ok := readLogRecord(br)
+		if !ok {
+			return offset, nil
+		}
+		pr := &snapshotReader{r: bytes.NewReader(payload)}
+		op := pr.readByte()
+		if op == logDel {
+			key := string(pr.readBytes(pr.readLen()))
+			if pr.err != nil {
+				return offset, nil
+			}
+			c.shardedMap.Del(key)
+			offset += n
+			continue
+		}
+		key, ci := pr.readEntry()
+		if op == logCounter {
+			count := pr.readVarint()
+			ci.value, ci.counter = nil, &count
+		} else if op != logSet {
+			pr.err = fmt.Errorf("unknown operation %d", op)
+		}
+		if pr.err != nil {
+			return offset, nil
+		}
+		offset += n
+
+		if hasExpired(now.Unix(), ci.expireAt) {
+			c.shardedMap.Del(key)
+			continue
+		}
+		if c.refreshAfter > 0 {
+			ci.refreshAt = now.Add(c.refreshAfter).Unix()
+		}
+		if err := c.shardedMap.Set(key, ci); err != nil && !errors.Is(err, ErrTooLarge) {
+			return 0, err
+		}
+	}
+}
+
+// readLogRecord reads a record and returns its payload and its size, ok is
+// false at the end of the log or at an invalid record.
+func readLogRecord(r *bufio.Reader) (payload []byte, size int64, ok bool) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > math.MaxInt32 {
+		return nil, 0, false
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		return nil, 0, false
+	}
+	var sum uint32
+	if err := binary.Read(r, binary.BigEndian, &sum); err != nil || sum != crc32.ChecksumIEEE(buf.Bytes()) {
+		return nil, 0, false
+	}
+	var varint [binary.MaxVarintLen64]byte
+	return buf.Bytes(), int64(binary.PutUvarint(varint[:], n)) + int64(n) + 4, true
+}
+
+// set records that key was set to ci.
+func (l *appendLog) set(key string, ci *cacheItem) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.payload.Reset()
+	encodeLogSet(&l.payload, key, ci)
+	l.appendLocked(l.payload.Bytes())
+}
+
+// del records that key was removed, whether deleted, expired or evicted.
+func (l *appendLog) del(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.payload.Reset()
+	pw := &snapshotWriter{w: &l.payload}
+	pw.writeByte(logDel)
+	pw.writeBytes([]byte(key))
+	l.appendLocked(l.payload.Bytes())
+}
+
+func encodeLogSet(buf *bytes.Buffer, key string, ci *cacheItem) {
+	pw := &snapshotWriter{w: buf}
+	if ci.counter == nil {
+		pw.writeByte(logSet)
+		pw.writeEntry(key, ci.value, ci)
+		return
+	}
+	pw.writeByte(logCounter)
+	pw.writeEntry(key, nil, ci)
+	pw.writeVarint(atomic.LoadInt64(ci.counter))
+}
+
+func (l *appendLog) appendLocked(payload []byte) {
+	if l.closed || l.err != nil {
+		return
+	}
+	n, err := writeLogRecord(l.w, payload)
+	l.size += n
+	if l.rewrite != nil {
+		writeLogRecord(l.rewrite, payload)
+	}
+	if err == nil && l.fsync == FsyncAlways {
+		err = l.syncLocked()
+	}
+	l.err = err
+}
+
+func writeLogRecord(w io.Writer, payload []byte) (int64, error) {
+	var buf [binary.MaxVarintLen64 + 4]byte
+	n, err := w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(payload)))])
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := w.Write(payload)
+	if err != nil {
+		return int64(n + m), err
+	}
+	binary.BigEndian.PutUint32(buf[:4], crc32.ChecksumIEEE(payload))
+	k, err := w.Write(buf[:4])
+	return int64(n + m + k), err
+}
+
+func (l *appendLog) syncLocked() error {
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// run flushes the log every second and compacts it when it grew enough.
+func (l *appendLog) run(c *Cache) {
+	defer close(l.stopped)
+	ticker := time.NewTicker(logFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.mu.Lock()
+			var file *os.File
+			if !l.closed && l.err == nil {
+				l.err = l.w.Flush()
+				if l.err == nil && l.fsync == FsyncEverySecond {
+					file = l.file
+				}
+			}
+			compact := l.size >= logCompactMinSize && l.size >= 2*l.compactedSize
+			l.mu.Unlock()
+			if file != nil {
+				l.sync(file)
+			}
+			if compact {
+				// a failed compaction leaves the log as it was, it is
+				// retried on the next tick
+				_ = l.compact(c)
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// sync syncs file without holding mu, so that the shards appending to the log
+// with their lock held do not wait for the disk.
+func (l *appendLog) sync(file *os.File) {
+	err := file.Sync()
+	if err == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// a compaction may have replaced and closed file, the new one is synced
+	if l.err == nil && l.file == file {
+		l.err = err
+	}
+}
+
+// compact rewrites the log with a record per live entry. The entries are read
+// without locking the shards, the records appended meanwhile are kept aside
+// and added after them, so that replaying the new log gives the same result.
+func (l *appendLog) compact(c *Cache) error {
+	l.mu.Lock()
+	if l.closed || l.err != nil || l.rewrite != nil {
+		l.mu.Unlock()
+		return l.err
+	}
+	l.rewrite = &bytes.Buffer{}
+	l.mu.Unlock()
+
+	tmp := l.path + ".compact"
+	file, size, err := c.writeCompactedLog(tmp)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rewrite := l.rewrite
+	l.rewrite = nil
+	if err == nil && l.closed {
+		err = ErrClosed
+	}
+	if err == nil {
+		var n int64
+		n, err = rewrite.WriteTo(file)
+		size += n
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, l.path)
+	}
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		os.Remove(tmp)
+		return err
+	}
+	syncDir(filepath.Dir(l.path))
+
+	// the buffered records are in the new log already
+	l.file.Close()
+	l.file = file
+	l.w.Reset(file)
+	l.size = size
+	l.compactedSize = size
+	return nil
+}
+
+// writeCompactedLog writes the header and a record per live entry to a new
+// file at path.
+func (c *Cache) writeCompactedLog(path string) (*os.File, int64, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	w := bufio.NewWriter(file)
+	n, err := w.WriteString(logMagic + string([]byte{logVersion}))
+	size := int64(n)
+
+	var payload bytes.Buffer
+	now := c.clock.Now().Unix()
+	for _, s := range c.shardedMap.shards {
+		if err != nil {
+			break
+		}
+		s.items.Range(func(key, value any) bool {
+			ci := value.(*cacheItem)
+			if hasExpired(now, ci.expireAt) {
+				return true
+			}
+			payload.Reset()
+			encodeLogSet(&payload, key.(string), ci)
+			var n int64
+			n, err = writeLogRecord(w, payload.Bytes())
+			size += n
+			return err == nil
+		})
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, size, nil
+}
+
+// close flushes and syncs the log, then returns its first write error.
+func (l *appendLog) close() error {
+	close(l.done)
+	<-l.stopped
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		if err := l.w.Flush(); err != nil {
+			l.err = err
+		} else if l.fsync != FsyncNever {
+			l.err = l.file.Sync()
+		}
+	}
+	l.closed = true
+	if err := l.file.Close(); err != nil && l.err == nil {
+		l.err = err
+	}
+	return l.err
+}
+
+// syncDir makes a rename in dir durable, where the platform supports it.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	d.Close()
+}
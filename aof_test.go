@@ -0,0 +1,165 @@
+package fastlocalcache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheAppendOnlyFile(t *testing.T) {
+	for name, fsync := range map[string]FsyncPolicy{
+		"always":      FsyncAlways,
+		"everysecond": FsyncEverySecond,
+		"never":       FsyncNever,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cache.aof")
+			clock := newFakeClock()
+			cache, err := OpenCache(Config{Clock: clock}, path, fsync)
+			assert.Nil(t, err)
+
+			ttl := time.Minute
+			for i := 0; i < 100; i++ {
+				assert.Nil(t, cache.Set(fmt.Sprintf("t-%d", i), i, nil))
+			}
+			cache.Del("t-1")
+			assert.Equal(t, 11, cache.DeletePrefix("t-2"))
+			assert.Nil(t, cache.Set("short", "s", &ttl))
+			assert.Nil(t, cache.SetWithTags("tagged", "v", nil, "tag"))
+			_, err = cache.IncrBy("counter", 5, nil)
+			assert.Nil(t, err)
+			_, err = cache.IncrBy("counter", 2, nil)
+			assert.Nil(t, err)
+			assert.Nil(t, cache.Close())
+
+			// entries expired during the downtime are not restored
+			clock.Add(ttl + time.Second)
+			restored, err := OpenCache(Config{Clock: clock}, path, fsync)
+			assert.Nil(t, err)
+			defer restored.Close()
+			assert.Equal(t, int64(100-12+2), restored.Len())
+
+			var n int
+			assert.ErrorIs(t, restored.Get("t-1", &n), ErrNotFound)
+			assert.ErrorIs(t, restored.Get("t-20", &n), ErrNotFound)
+			assert.Nil(t, restored.Get("t-42", &n))
+			assert.Equal(t, 42, n)
+			assert.Nil(t, restored.Get("counter", &n))
+			assert.Equal(t, 7, n)
+			count, err := restored.IncrBy("counter", 1, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(8), count)
+			assert.Equal(t, 1, restored.InvalidateTag("tag"))
+		})
+	}
+}
+
+func TestCacheAppendOnlyFileTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	cache, err := OpenCache(Config{}, path, FsyncAlways)
+	assert.Nil(t, err)
+	assert.Nil(t, cache.Set("t-1", 1, nil))
+	assert.Nil(t, cache.Set("t-2", 2, nil))
+	assert.Nil(t, cache.Close())
+
+	// a crash in the middle of the last record
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Nil(t, os.Truncate(path, info.Size()-3))
+
+	cache, err = OpenCache(Config{}, path, FsyncAlways)
+	assert.Nil(t, err)
+	var n int
+	assert.Nil(t, cache.Get("t-1", &n))
+	assert.ErrorIs(t, cache.Get("t-2", &n), ErrNotFound)
+	// the torn record was cut off, so new records are readable
+	assert.Nil(t, cache.Set("t-3", 3, nil))
+	assert.Nil(t, cache.Close())
+
+	cache, err = OpenCache(Config{}, path, FsyncEverySecond)
+	assert.Nil(t, err)
+	defer cache.Close()
+	assert.Equal(t, int64(2), cache.Len())
+
+	// a foreign file is not overwritten
+	foreign := filepath.Join(t.TempDir(), "foreign")
+	assert.Nil(t, os.WriteFile(foreign, []byte("not a log"), 0o644))
+	_, err = OpenCache(Config{}, foreign, FsyncEverySecond)
+	assert.NotNil(t, err)
+}
+
+func TestCacheAppendOnlyFileTornHeader(t *testing.T) {
+	header := logMagic + string([]byte{logVersion})
+	for n := 1; n < len(header); n++ {
+		// a crash while the log was created, it is treated as empty
+		path := filepath.Join(t.TempDir(), "cache.aof")
+		assert.Nil(t, os.WriteFile(path, []byte(header[:n]), 0o644))
+		cache, err := OpenCache(Config{}, path, FsyncEverySecond)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), cache.Len())
+		assert.Nil(t, cache.Set("t-1", 1, nil))
+		assert.Nil(t, cache.Close())
+
+		cache, err = OpenCache(Config{}, path, FsyncEverySecond)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), cache.Len())
+		assert.Nil(t, cache.Close())
+	}
+
+	// a short file that is not a log is not overwritten
+	foreign := filepath.Join(t.TempDir(), "foreign")
+	assert.Nil(t, os.WriteFile(foreign, []byte("FLx"), 0o644))
+	_, err := OpenCache(Config{}, foreign, FsyncEverySecond)
+	assert.NotNil(t, err)
+}
+
+func TestCacheAppendOnlyFileCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	cache, err := OpenCache(Config{}, path, FsyncNever)
+	assert.Nil(t, err)
+
+	// overwrite the same keys so that most records are obsolete
+	for round := 0; round < 20; round++ {
+		for i := 0; i < 100; i++ {
+			assert.Nil(t, cache.Set(fmt.Sprintf("t-%d", i), round, nil))
+		}
+	}
+	before := cache.log.size
+
+	// writes during the compaction are kept
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			assert.Nil(t, cache.Set(fmt.Sprintf("t-%d", i), 100+i, nil))
+			cache.Del(fmt.Sprintf("t-%d", i/10))
+		}
+	}()
+	assert.Nil(t, cache.log.compact(cache))
+	wg.Wait()
+	assert.Less(t, cache.log.size, before)
+	want := map[string]string{}
+	cache.Range(func(key string, raw []byte, _ time.Time) bool {
+		want[key] = string(raw)
+		return true
+	})
+	assert.Nil(t, cache.Close())
+	_, err = os.Stat(path + ".compact")
+	assert.True(t, os.IsNotExist(err))
+
+	restored, err := OpenCache(Config{}, path, FsyncEverySecond)
+	assert.Nil(t, err)
+	defer restored.Close()
+	got := map[string]string{}
+	restored.Range(func(key string, raw []byte, _ time.Time) bool {
+		got[key] = string(raw)
+		return true
+	})
+	assert.Equal(t, want, got)
+}
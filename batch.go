@@ -0,0 +1,94 @@
+package fastlocalcache
+
+import "time"
+
+// MGet gets the values of keys like Get, unmarshaling keys[i] into values[i].
+// Reads take no lock, so unlike MSet and MDel the keys are not grouped by
+// shard and each is hashed only once. The returned slice holds the error of
+// each key, nil on a hit.
+func (c *Cache) MGet(keys []string, values []any) []error {
+	if len(keys) != len(values) {
+		panic("keys and values differ in length")
+	}
+	errs := make([]error, len(keys))
+	if c.isClosed() {
+		fillErrors(errs, ErrClosed)
+		return errs
+	}
+
+	now := c.clock.Now().Unix()
+	for i, key := range keys {
+		ci, err := c.getItem(key, now)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		errs[i] = c.unmarshalItem(ci, values[i])
+	}
+	return errs
+}
+
+// MSet stores values[i] under keys[i] like Set, all with the same expiration.
+// Each shard is locked once for all of its keys and the returned slice holds
+// the error of each key.
+func (c *Cache) MSet(keys []string, values []any, expiration *time.Duration) []error {
+	if len(keys) != len(values) {
+		panic("keys and values differ in length")
+	}
+	errs := make([]error, len(keys))
+	if c.isClosed() {
+		fillErrors(errs, ErrClosed)
+		return errs
+	}
+
+	items := make([]*cacheItem, len(keys))
+	for i, value := range values {
+		bs, err := c.serializer.Marshal(value)
+		if err != nil {
+			errs[i] = &SerializationError{Op: "marshal", Err: err}
+			continue
+		}
+		items[i] = c.newItem(bs, expiration)
+	}
+	for s, positions := range c.shardedMap.groupByShard(keys) {
+		s.mu.Lock()
+		for _, i := range positions {
+			if items[i] != nil {
+				errs[i] = s.setLocked(keys[i], items[i])
+			}
+		}
+		s.mu.Unlock()
+	}
+	return errs
+}
+
+// MDel deletes keys, locking each shard once for all of its keys.
+func (c *Cache) MDel(keys []string) {
+	if c.isClosed() {
+		return
+	}
+	for s, positions := range c.shardedMap.groupByShard(keys) {
+		s.mu.Lock()
+		for _, i := range positions {
+			s.delLocked(keys[i])
+		}
+		s.mu.Unlock()
+	}
+}
+
+func fillErrors(errs []error, err error) {
+	for i := range errs {
+		errs[i] = err
+	}
+}
+
+// groupByShard returns the positions of keys grouped by the shard owning
+// them.
+func (m *shardedMap) groupByShard(keys []string) map[*shard][]int {
+	groups := make(map[*shard][]int)
+	for i, key := range keys {
+		s := m.getShard(key)
+		groups[s] = append(groups[s], i)
+	}
+	return groups
+}
@@ -0,0 +1,69 @@
+package fastlocalcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheBatch(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithClock(clock))
+	defer cache.Close()
+
+	keys := make([]string, 100)
+	values := make([]any, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("t-%d", i)
+		values[i] = i
+	}
+	values[7] = make(chan int)
+	ttl := time.Minute
+	errs := cache.MSet(keys, values, &ttl)
+	for i, err := range errs {
+		if i == 7 {
+			assert.IsType(t, &SerializationError{}, err)
+		} else {
+			assert.Nil(t, err)
+		}
+	}
+	assert.Equal(t, int64(99), cache.Len())
+
+	// results line up with the keys
+	got := make([]int, 101)
+	dests := make([]any, 101)
+	for i := range dests {
+		dests[i] = &got[i]
+	}
+	errs = cache.MGet(append(keys, "t-missing"), dests)
+	for i, err := range errs {
+		if i == 7 || i == 100 {
+			assert.ErrorIs(t, err, ErrNotFound)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, i, got[i])
+	}
+
+	// misses allocate the returned slice only
+	allocs := testing.AllocsPerRun(100, func() {
+		cache.MGet([]string{"t-missing"}, dests[:1])
+	})
+	assert.Equal(t, float64(1), allocs)
+
+	// the shared expiration applies to every key
+	clock.Add(ttl + time.Second)
+	for _, err := range cache.MGet(keys[:10], dests[:10]) {
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+
+	cache.MDel(keys)
+	assert.Equal(t, int64(0), cache.Len())
+
+	cache.Close()
+	for _, err := range cache.MSet(keys[:3], values[:3], nil) {
+		assert.ErrorIs(t, err, ErrClosed)
+	}
+}
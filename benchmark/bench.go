@@ -59,6 +59,12 @@ var (
 		1,
 		"The goroutine multiplier (see runtime.GOMAXPROCS()).",
 	)
+	// POLICY is the eviction policy used by fastlocalcache.
+	flagPolicy = flag.String(
+		"policy",
+		"tinylfu",
+		`Eviction policy of fastlocalcache: "lru", "lfu", "fifo", "tinylfu", "arc", "s3fifo" or "sieve".`,
+	)
 )
 
 // Benchmark is used to generate benchmarks.
@@ -93,11 +99,11 @@ func NewBenchmarks(kind string, para, capa int, cache *benchCache) []*Benchmark
 	if kind == "hits" || kind == "all" {
 		suite = append(suite, []*benchSuite{
 			//{"hits-zipf     ", HitsZipf, nil},
-			//{"hits-arc-p3   ", HitsARC("p3"), nil},
+			{"hits-arc-p3   ", HitsARC("p3"), nil},
 			//{"hits-arc-p8   ", HitsARC("p8"), nil},
 			//{"hits-arc-s3   ", HitsARC("s3"), nil},
 			//{"hits-arc-ds1  ", HitsARC("ds1"), nil},
-			//{"hits-arc-oltp ", HitsARC("oltp"), nil},
+			{"hits-arc-oltp ", HitsARC("oltp"), nil},
 			{"hits-lirs-loop", HitsLIRS("loop"), nil},
 		}...)
 	}
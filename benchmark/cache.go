@@ -422,14 +422,29 @@ func (c *BenchGoburrow) Close() {}
 
 type BenchFastLocalCache struct {
 	cache *fastlocalcache.Cache
-	log   *policyLog
 	track bool
 }
 
+var fastLocalCachePolicies = map[string]fastlocalcache.PolicyFactory{
+	"lru":     fastlocalcache.NewLRUPolicy,
+	"lfu":     fastlocalcache.NewLFUPolicy,
+	"fifo":    fastlocalcache.NewFIFOPolicy,
+	"tinylfu": fastlocalcache.NewTinyLFUPolicy,
+	"arc":     fastlocalcache.NewARCPolicy,
+	"s3fifo":  fastlocalcache.NewS3FIFOPolicy,
+	"sieve":   fastlocalcache.NewSIEVEPolicy,
+}
+
 func NewBenchFastLocalCache(capacity int, track bool) Cache {
+	policy, ok := fastLocalCachePolicies[*flagPolicy]
+	if !ok {
+		log.Panicf("unknown policy %q", *flagPolicy)
+	}
 	return &BenchFastLocalCache{
-		cache: fastlocalcache.NewCache(),
-		log:   &policyLog{},
+		cache: fastlocalcache.NewCacheWithConfig(fastlocalcache.Config{
+			Policy:     policy,
+			MaxEntries: int64(capacity),
+		}),
 		track: track,
 	}
 }
@@ -445,13 +460,9 @@ func (c *BenchFastLocalCache) Get(key string) (interface{}, bool) {
 
 func (c *BenchFastLocalCache) Set(key string, value interface{}) {
 	if c.track {
+		// counted by the cache stats
 		var v interface{}
-		err := c.cache.Get(key, &v)
-		if err != nil {
-			c.log.Miss()
-		} else {
-			c.log.Hit()
-		}
+		c.cache.Get(key, &v)
 	}
 	c.cache.Set(key, value, nil)
 }
@@ -461,7 +472,14 @@ func (c *BenchFastLocalCache) Del(key string) {
 }
 
 func (c *BenchFastLocalCache) Log() *policyLog {
-	return c.log
+	stats := c.cache.Stats()
+	return &policyLog{
+		hits:      int64(stats.Hits),
+		misses:    int64(stats.Misses),
+		evictions: int64(stats.Evictions),
+	}
 }
 
-func (c *BenchFastLocalCache) Close() {}
+func (c *BenchFastLocalCache) Close() {
+	c.cache.Close()
+}
@@ -1,133 +1,482 @@
 package fastlocalcache
 
 import (
-	"errors"
-	"fmt"
-	"os"
-	"os/signal"
+	"context"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"time"
 )
 
 const (
 	shardsCount int64 = 256
 	neverExpire int64 = -1
+	// entryOverhead approximates the memory held by an entry besides its key
+	// and value: the cacheItem, the sync.Map entry and the eviction bookkeeping.
+	entryOverhead int64 = 128
+	// expireInterval is the default interval of the expirer, it matches the
+	// tick of the timing wheel.
+	expireInterval = time.Second
 )
 
 type Cache struct {
-	serializer Serializer
-	shardedMap *shardedMap
+	serializer   Serializer
+	shardedMap   *shardedMap
+	clock        Clock
+	refreshAfter time.Duration
+	staleWindow  int64 // seconds
+	loads        loadGroup
+	log          *appendLog // nil unless created by OpenCache
+	closed       int32
+	done         chan struct{}
 }
 
-func NewCache() *Cache {
-	c := &Cache{
-		serializer: JSONSerializer{},
-		shardedMap: newShardedMap(),
+// Config controls a Cache, the zero value means an unlimited cache with the
+// defaults described on each field.
+type Config struct {
+	// Serializer encodes the values, JSONSerializer by default.
+	Serializer Serializer
+	// Shards is the number of shards, 256 by default.
+	Shards int64
+	// HashFunc picks the shard of a key, KeyToHash by default.
+	HashFunc func(key string) uint64
+	// CleanupInterval is how often expired entries are removed in the
+	// background, one second by default.
+	CleanupInterval time.Duration
+	// Clock tells the current time, the system clock by default.
+	Clock Clock
+
+	// MaxEntries is the maximum number of entries, entries are evicted when
+	// a Set would exceed it.
+	MaxEntries int64
+	// MaxBytes is the maximum memory used by entries, counting the key, the
+	// serialized value, the tags and entryOverhead for each entry. Entries
+	// are evicted when a Set would exceed it.
+	MaxBytes int64
+	// Policy chooses the evicted entries, NewLRUPolicy by default.
+	Policy PolicyFactory
+
+	// RefreshAfter is the age after which GetOrLoad still returns an entry
+	// but reloads it in the background, 0 disables refreshing ahead.
+	RefreshAfter time.Duration
+	// StaleWhileRevalidate keeps entries for this long after they expire:
+	// Get misses them, but GetOrLoad returns them when its loader fails.
+	StaleWhileRevalidate time.Duration
+
+	// PrefixIndex keeps the keys of each shard in a radix tree, so that
+	// ScanPrefix and DeletePrefix only visit the matching keys instead of
+	// all of them, at the cost of memory and slower writes.
+	PrefixIndex bool
+
+	// Context bounds the lifetime of the cache, it is closed when the
+	// context is done. Close has to be called if it is nil.
+	Context context.Context
+}
+
+func NewCache(opts ...Option) *Cache {
+	var config Config
+	for _, opt := range opts {
+		opt(&config)
 	}
-	go c.scanAndExpire()
+	return NewCacheWithConfig(config)
+}
+
+// NewCacheWithConfig creates a cache from config, see OpenCache for one that
+// survives restarts.
+func NewCacheWithConfig(config Config) *Cache {
+	config = config.withDefaults()
+	c := newCache(config)
+	go runExpirer(config.Context, config.CleanupInterval, c.done, c.expire, c.Close)
 	return c
 }
 
+// OpenCache creates a cache like NewCacheWithConfig that logs its writes to
+// the append-only file at path, synced to disk as fsync tells. The log is
+// replayed first, so that entries survive a restart, and compacted in the
+// background. It returns the error of opening or replaying the log.
+func OpenCache(config Config, path string, fsync FsyncPolicy) (*Cache, error) {
+	config = config.withDefaults()
+	c := newCache(config)
+	log, err := openAppendLog(c, path, fsync)
+	if err != nil {
+		return nil, err
+	}
+	c.log = log
+	go runExpirer(config.Context, config.CleanupInterval, c.done, c.expire, c.Close)
+	return c, nil
+}
+
+func newCache(config Config) *Cache {
+	return &Cache{
+		serializer:   config.Serializer,
+		shardedMap:   newShardedMap(config),
+		clock:        config.Clock,
+		refreshAfter: config.RefreshAfter,
+		staleWindow:  staleSeconds(config.StaleWhileRevalidate),
+		done:         make(chan struct{}),
+	}
+}
+
+func (config Config) withDefaults() Config {
+	if config.Serializer == nil {
+		config.Serializer = JSONSerializer{}
+	}
+	if config.Shards <= 0 {
+		config.Shards = shardsCount
+	}
+	if config.HashFunc == nil {
+		config.HashFunc = KeyToHash
+	}
+	if config.CleanupInterval <= 0 {
+		config.CleanupInterval = expireInterval
+	}
+	if config.Clock == nil {
+		config.Clock = systemClock{}
+	}
+	if config.Context == nil {
+		config.Context = context.Background()
+	}
+	return config
+}
+
+// staleSeconds rounds the stale window up to whole seconds, the resolution of
+// expireAt.
+func staleSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
 func hasExpired(now, expireAt int64) bool {
 	return expireAt != neverExpire && now > expireAt
 }
 
-func (c *Cache) scanAndExpire() {
-	quitChannel := make(chan os.Signal)
-	signal.Notify(quitChannel, syscall.SIGINT, syscall.SIGTERM)
+// expire removes the expired items, Get removes the ones it finds in between.
+func (c *Cache) expire() {
+	c.shardedMap.expire(c.clock.Now().Unix())
+}
+
+// runExpirer calls expire every interval until done is closed, or calls
+// closeCache once ctx is done.
+func runExpirer(ctx context.Context, interval time.Duration, done <-chan struct{}, expire func(), closeCache func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
 		select {
-		case <-time.After(time.Minute):
-			c.shardedMap.scanAndExpire()
-		case <-quitChannel:
-			break
+		case <-ticker.C:
+			expire()
+		case <-ctx.Done():
+			closeCache()
+			return
+		case <-done:
+			return
 		}
 	}
 }
 
+// Close stops the background expirer and drops all entries, operations on a
+// closed Cache return ErrClosed and loads finishing after Close are not
+// stored. Closing twice is a no-op. For a cache from OpenCache, the log is
+// synced and closed first and its first write error, if any, is returned.
+func (c *Cache) Close() error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return nil
+	}
+	close(c.done)
+	var err error
+	if c.log != nil {
+		err = c.log.close()
+	}
+	c.shardedMap.clear()
+	return err
+}
+
+func (c *Cache) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
+
+// Get unmarshals the value of key into value. A miss returns ErrNotFound or
+// ErrExpired and a value that cannot be decoded a *SerializationError.
 func (c *Cache) Get(key string, value any) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+
+	ci, err := c.getItem(key, c.clock.Now().Unix())
+	if err != nil {
+		return err
+	}
+	return c.unmarshalItem(ci, value)
+}
+
+// getItem returns the item of key. An expired item is returned along with
+// ErrExpired while it is within the stale window, and deleted afterwards.
+func (c *Cache) getItem(key string, now int64) (*cacheItem, error) {
 	// get from store
-	ci, ok := c.shardedMap.Get(key)
+	s := c.shardedMap.getShard(key)
+	ci, ok := s.get(key)
 	if !ok {
-		return errors.New("missing key")
+		atomic.AddUint64(&s.stats.misses, 1)
+		return nil, ErrNotFound
 	}
 
 	// delete expired key
-	if hasExpired(time.Now().Unix(), ci.expireAt) {
-		c.shardedMap.Del(key)
-		return errors.New("missing key")
+	if hasExpired(now, ci.expireAt) {
+		atomic.AddUint64(&s.stats.misses, 1)
+		atomic.AddUint64(&s.stats.expiredReads, 1)
+		if hasExpired(now, ci.expireAt+c.staleWindow) {
+			s.delIfSame(key, ci)
+			return nil, ErrExpired
+		}
+		return ci, ErrExpired
+	}
+	atomic.AddUint64(&s.stats.hits, 1)
+	return ci, nil
+}
+
+// unmarshalItem unmarshals the value of ci into value, the current count of a
+// counter is marshaled first.
+func (c *Cache) unmarshalItem(ci *cacheItem, value any) error {
+	bs, err := c.itemBytes(ci)
+	if err != nil {
+		return err
 	}
+	return c.unmarshal(bs, value)
+}
 
-	// unmarshal
-	err := c.serializer.Unmarshal(ci.value, value)
+// itemBytes returns the serialized value of ci.
+func (c *Cache) itemBytes(ci *cacheItem) ([]byte, error) {
+	if ci.counter == nil {
+		return ci.value, nil
+	}
+	bs, err := c.serializer.Marshal(atomic.LoadInt64(ci.counter))
 	if err != nil {
-		return fmt.Errorf("unmarshal error: %w", err)
+		return nil, &SerializationError{Op: "marshal", Err: err}
 	}
+	return bs, nil
+}
 
+func (c *Cache) unmarshal(bs []byte, value any) error {
+	if err := c.serializer.Unmarshal(bs, value); err != nil {
+		return &SerializationError{Op: "unmarshal", Err: err}
+	}
 	return nil
 }
 
+// Set stores value under key, it never expires if expiration is nil. A value
+// that cannot be encoded returns a *SerializationError and an entry larger
+// than Config.MaxBytes ErrTooLarge.
 func (c *Cache) Set(key string, value any, expiration *time.Duration) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+
 	// marshal
 	bs, err := c.serializer.Marshal(value)
 	if err != nil {
-		return fmt.Errorf("marshal error: %w", err)
+		return &SerializationError{Op: "marshal", Err: err}
 	}
 
-	// set to store
+	return c.setBytes(key, bs, expiration)
+}
+
+// setBytes stores an already serialized value.
+func (c *Cache) setBytes(key string, bs []byte, expiration *time.Duration) error {
+	return c.shardedMap.Set(key, c.newItem(bs, expiration))
+}
+
+func (c *Cache) newItem(bs []byte, expiration *time.Duration) *cacheItem {
+	now := c.clock.Now()
 	ci := &cacheItem{
 		value: bs,
 	}
 	if expiration == nil {
 		ci.expireAt = neverExpire
 	} else {
-		ci.expireAt = time.Now().Add(*expiration).Unix()
+		ci.expireAt = now.Add(*expiration).Unix()
 	}
-	c.shardedMap.Set(key, ci)
-
-	return nil
+	if c.refreshAfter > 0 {
+		ci.refreshAt = now.Add(c.refreshAfter).Unix()
+	}
+	return ci
 }
 
 func (c *Cache) Len() int64 {
-	return c.shardedMap.len
+	return c.shardedMap.Len()
+}
+
+// Bytes returns the memory currently accounted to entries, see Config.MaxBytes.
+func (c *Cache) Bytes() int64 {
+	return c.shardedMap.Bytes()
 }
 
 func (c *Cache) Del(key string) {
+	if c.isClosed() {
+		return
+	}
 	c.shardedMap.Del(key)
 }
 
 type cacheItem struct {
-	value    []byte
-	expireAt int64 // unix timestamp, in seconds
+	value     []byte
+	counter   *int64               // non-nil for counters, which have no value
+	tags      []string             // see SetWithTags
+	visited   *visitedNode         // policy node of the key, nil unless it is a visitedPolicy
+	expireAt  int64                // unix timestamp, in seconds
+	refreshAt int64                // unix timestamp, in seconds, 0 if never refreshed
+	version   uint64               // assigned by the shard, increases with every write
+	timer     *timer[expiringItem] // nil if the item never expires
+}
+
+// expiringItem is what the timing wheel of a Cache expires.
+type expiringItem struct {
+	key  string
+	item *cacheItem
+}
+
+func entrySize(key string, ci *cacheItem) int64 {
+	size := int64(len(key)) + int64(len(ci.value)) + entryOverhead
+	for _, tag := range ci.tags {
+		size += int64(len(tag))
+	}
+	return size
 }
 
-func newShardedMap() *shardedMap {
-	shards := make([]*sync.Map, shardsCount)
-	for i := 0; i < int(shardsCount); i++ {
-		shards[i] = &sync.Map{}
+func newShardedMap(config Config) *shardedMap {
+	count := config.Shards
+	// every shard must be able to hold at least one entry
+	if config.MaxEntries > 0 && config.MaxEntries < count {
+		count = config.MaxEntries
+	}
+	var budget *byteBudget
+	if config.MaxBytes > 0 {
+		budget = &byteBudget{max: config.MaxBytes}
+	}
+	shards := make([]*shard, count)
+	for i := 0; i < int(count); i++ {
+		shards[i] = newShard(
+			splitCapacity(config.MaxEntries, count, int64(i)),
+			budget,
+			config.Policy,
+			config.Clock,
+			staleSeconds(config.StaleWhileRevalidate),
+			config.PrefixIndex,
+		)
+	}
+	if budget != nil {
+		budget.shards = shards
 	}
 	return &shardedMap{
 		shards:      shards,
-		shardsCount: shardsCount,
-		keyToHash:   KeyToHash,
+		shardsCount: count,
+		keyToHash:   config.HashFunc,
 	}
 }
 
+// splitCapacity returns the part of total owned by the i-th of count shards,
+// the parts sum up to total exactly.
+func splitCapacity(total, count, i int64) int64 {
+	if total <= 0 {
+		return 0
+	}
+	part := total / count
+	if i < total%count {
+		part++
+	}
+	return part
+}
+
 type shardedMap struct {
-	shards      []*sync.Map
+	shards      []*shard
 	shardsCount int64
-	len         int64 // 实际存储的key的数量，包括失效的
 	keyToHash   func(key string) uint64
 }
 
-func (m *shardedMap) getShard(key string) *sync.Map {
+func (m *shardedMap) getShard(key string) *shard {
 	hash := m.keyToHash(key)
 	return m.shards[hash%uint64(m.shardsCount)]
 }
 
 func (m *shardedMap) Get(key string) (*cacheItem, bool) {
-	value, ok := m.getShard(key).Load(key)
+	return m.getShard(key).get(key)
+}
+
+func (m *shardedMap) Set(key string, value *cacheItem) error {
+	return m.getShard(key).set(key, value)
+}
+
+func (m *shardedMap) Del(key string) {
+	m.getShard(key).del(key)
+}
+
+// Len 实际存储的key的数量，包括失效的
+func (m *shardedMap) Len() int64 {
+	var n int64
+	for _, s := range m.shards {
+		n += atomic.LoadInt64(&s.len)
+	}
+	return n
+}
+
+func (m *shardedMap) Bytes() int64 {
+	var n int64
+	for _, s := range m.shards {
+		n += atomic.LoadInt64(&s.bytes)
+	}
+	return n
+}
+
+// clear removes all items.
+func (m *shardedMap) clear() {
+	for _, s := range m.shards {
+		s.clear()
+	}
+}
+
+// expire removes the items whose timer fired by now, only due items are
+// visited.
+func (m *shardedMap) expire(now int64) {
+	for _, s := range m.shards {
+		s.expire(now)
+	}
+}
+
+// shard reads are lock free, writes are serialized by mu so that the length,
+// the bytes and the eviction policy stay consistent with items.
+type shard struct {
+	mu      sync.Mutex
+	items   sync.Map
+	len     int64
+	bytes   int64
+	maxLen  int64                      // 0 means unlimited
+	budget  *byteBudget                // nil if unlimited
+	policy  EvictionPolicy             // nil if unlimited
+	visited visitedPolicy              // policy if it is a visitedPolicy
+	wheel   *timingWheel[expiringItem] // created by the first write with an expiry
+	clock   Clock
+	stale   int64                          // seconds expired items are kept for
+	version uint64                         // last version assigned
+	index   *radixTree                     // keys of items, nil unless Config.PrefixIndex
+	tags    map[string]map[string]struct{} // keys by tag
+	log     *appendLog                     // nil unless created by OpenCache
+	stats   shardStats
+	closed  bool // set by clear, writes fail with ErrClosed after it
+}
+
+func newShard(maxLen int64, budget *byteBudget, newPolicy PolicyFactory, clock Clock, stale int64, prefixIndex bool) *shard {
+	s := &shard{maxLen: maxLen, budget: budget, clock: clock, stale: stale}
+	if prefixIndex {
+		s.index = &radixTree{}
+	}
+	if maxLen > 0 || budget != nil {
+		if newPolicy == nil {
+			newPolicy = NewLRUPolicy
+		}
+		s.policy = newPolicy(maxLen)
+		s.visited, _ = s.policy.(visitedPolicy)
+	}
+	return s
+}
+
+func (s *shard) get(key string) (*cacheItem, bool) {
+	value, ok := s.items.Load(key)
 	if !ok {
 		return nil, false
 	}
@@ -135,44 +484,228 @@ func (m *shardedMap) Get(key string) (*cacheItem, bool) {
 	if !ok {
 		panic("unsupported value")
 	}
+	s.access(key, ci)
 	return ci, true
 }
 
-func (m *shardedMap) Set(key string, value *cacheItem) {
-	_, loaded := m.getShard(key).Swap(key, value)
-	if !loaded {
-		m.len++
+// access tells the eviction policy that key, stored as ci, was used.
+func (s *shard) access(key string, ci *cacheItem) {
+	if ci.visited != nil {
+		ci.visited.hit()
+	} else if s.policy != nil {
+		s.policy.Access(key)
 	}
 }
 
-func (m *shardedMap) Del(key string) {
-	m.del(key)
+func (s *shard) set(key string, value *cacheItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setLocked(key, value)
 }
 
-func (m *shardedMap) del(key string) {
-	_, loaded := m.getShard(key).LoadAndDelete(key)
+func (s *shard) setLocked(key string, value *cacheItem) error {
+	if s.closed {
+		return ErrClosed
+	}
+	size := entrySize(key, value)
+	if s.budget != nil && size > s.budget.max {
+		return ErrTooLarge
+	}
+
+	atomic.AddUint64(&s.stats.sets, 1)
+	s.version++
+	value.version = s.version
+	if value.expireAt != neverExpire {
+		// each shard has a wheel of its own, so that scheduling does not
+		// contend across shards
+		if s.wheel == nil {
+			s.wheel = newTimingWheel[expiringItem](s.clock.Now().Unix())
+		}
+		value.timer = s.wheel.schedule(expiringItem{key, value}, value.expireAt+s.stale)
+	}
+	if s.visited != nil {
+		// set before value is published, since readers hit it without locking
+		if old, ok := s.items.Load(key); ok {
+			value.visited = old.(*cacheItem).visited
+		} else {
+			value.visited = s.visited.addVisited(key)
+		}
+	}
+	old, loaded := s.items.Swap(key, value)
+	if s.log != nil {
+		s.log.set(key, value)
+	}
 	if loaded {
-		m.len--
+		oldItem := old.(*cacheItem)
+		if oldItem.timer != nil {
+			s.wheel.cancel(oldItem.timer)
+		}
+		s.untagLocked(key, oldItem)
+		s.addBytes(size - entrySize(key, oldItem))
+		s.access(key, value)
+	} else {
+		atomic.AddInt64(&s.len, 1)
+		s.addBytes(size)
+		if s.index != nil {
+			s.index.insert(key)
+		}
+		if s.policy != nil && s.visited == nil {
+			s.policy.Add(key)
+		}
+	}
+	s.tagLocked(key, value)
+	if s.policy == nil {
+		return nil
+	}
+	// the last entry of the shard, likely the one just written, is only
+	// evicted for the budget once the other shards have nothing to give
+	for s.overflowed() && atomic.LoadInt64(&s.len) > 1 {
+		if !s.evictLocked() {
+			break
+		}
+	}
+	if s.budget != nil && s.budget.overflowed() {
+		s.budget.reclaim(s)
+		for s.budget.overflowed() {
+			if !s.evictLocked() {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// evictLocked evicts the victim of the policy, it returns false if there is
+// none.
+func (s *shard) evictLocked() bool {
+	victim, ok := s.policy.Evict()
+	if !ok {
+		return false
+	}
+	if ci, loaded := s.items.LoadAndDelete(victim); loaded {
+		atomic.AddUint64(&s.stats.evictions, 1)
+		s.removedLocked(victim, ci.(*cacheItem), false)
+	}
+	return true
+}
+
+func (s *shard) overflowed() bool {
+	return (s.maxLen > 0 && atomic.LoadInt64(&s.len) > s.maxLen) ||
+		(s.budget != nil && s.budget.overflowed())
+}
+
+func (s *shard) addBytes(delta int64) {
+	atomic.AddInt64(&s.bytes, delta)
+	if s.budget != nil {
+		atomic.AddInt64(&s.budget.used, delta)
 	}
 }
 
-func (m *shardedMap) scanAndExpire() {
-	now := time.Now().Unix()
-	for _, shard := range m.shards {
-		shard.Range(func(key, value any) bool {
-			ci, ok := value.(*cacheItem)
-			if !ok {
-				panic("unsupported value")
+// byteBudget is the memory limit of Config.MaxBytes. It is shared by the
+// shards rather than split between them, so that an entry may use any part
+// of it.
+type byteBudget struct {
+	max    int64
+	used   int64
+	next   uint32 // shard reclaim starts from, so that shards take turns
+	shards []*shard
+}
+
+func (b *byteBudget) overflowed() bool {
+	return atomic.LoadInt64(&b.used) > b.max
+}
+
+// reclaim evicts from the shards other than s, which is locked and has at
+// most one entry left, until the budget is respected. Busy shards are
+// skipped rather than waited for, so that two shards reclaiming from each
+// other cannot deadlock; they are tried again on the next write.
+func (b *byteBudget) reclaim(s *shard) {
+	start := int(atomic.AddUint32(&b.next, 1))
+	for evicted := true; evicted && b.overflowed(); {
+		evicted = false
+		for i := range b.shards {
+			other := b.shards[(start+i)%len(b.shards)]
+			if other == s || !other.mu.TryLock() {
+				continue
+			}
+			if other.evictLocked() {
+				evicted = true
 			}
-			if hasExpired(now, ci.expireAt) {
-				keyStr, ok := key.(string)
-				if !ok {
-					panic("unsupported key type")
-				}
-				m.del(keyStr)
+			other.mu.Unlock()
+			if !b.overflowed() {
+				return
 			}
+		}
+	}
+}
+
+func (s *shard) del(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delLocked(key)
+}
+
+func (s *shard) delLocked(key string) {
+	if ci, loaded := s.items.LoadAndDelete(key); loaded {
+		atomic.AddUint64(&s.stats.deletes, 1)
+		s.removedLocked(key, ci.(*cacheItem), true)
+	}
+}
+
+func (s *shard) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// a write racing with Close, such as a loader finishing late, must not
+	// store into the cleared shard
+	s.closed = true
+	s.items.Range(func(key, value any) bool {
+		s.items.Delete(key)
+		s.removedLocked(key.(string), value.(*cacheItem), true)
+		return true
+	})
+}
 
-			return true
-		})
+// expire removes the items of s whose timer fired by now.
+func (s *shard) expire(now int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.wheel == nil {
+		return
+	}
+	for _, t := range s.wheel.advance(now) {
+		if s.items.CompareAndDelete(t.value.key, t.value.item) {
+			atomic.AddUint64(&s.stats.expirations, 1)
+			s.removedLocked(t.value.key, t.value.item, true)
+		}
+	}
+}
+
+// delIfSame deletes key only if it still maps to ci, so that a concurrent
+// Set of a fresh value is not lost.
+func (s *shard) delIfSame(key string, ci *cacheItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.items.CompareAndDelete(key, ci) {
+		s.removedLocked(key, ci, true)
+	}
+}
+
+// removedLocked updates the bookkeeping after key was removed from items,
+// forget is false when the policy already forgot the key by evicting it.
+func (s *shard) removedLocked(key string, ci *cacheItem, forget bool) {
+	atomic.AddInt64(&s.len, -1)
+	s.addBytes(-entrySize(key, ci))
+	if ci.timer != nil {
+		s.wheel.cancel(ci.timer)
+	}
+	if s.index != nil {
+		s.index.delete(key)
+	}
+	s.untagLocked(key, ci)
+	if s.log != nil {
+		s.log.del(key)
+	}
+	if forget && s.policy != nil {
+		s.policy.Remove(key)
 	}
 }
@@ -1,7 +1,13 @@
 package fastlocalcache
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -28,3 +34,136 @@ func TestCache(t *testing.T) {
 	getErr = cache.Get("t-1", str)
 	assert.NotNil(t, getErr)
 }
+
+func TestCacheMaxEntries(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxEntries: 300})
+	for i := 0; i < 3000; i++ {
+		setErr := cache.Set(fmt.Sprintf("t-%d", i), i, nil)
+		assert.Nil(t, setErr)
+		assert.LessOrEqual(t, cache.Len(), int64(300))
+	}
+	assert.Equal(t, int64(300), cache.Len())
+
+	// the most recent key survives eviction
+	var v int
+	getErr := cache.Get("t-2999", &v)
+	assert.Nil(t, getErr)
+	assert.Equal(t, 2999, v)
+}
+
+func TestCacheMaxEntriesFewerThanShards(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxEntries: 2})
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, cache.Set(fmt.Sprintf("t-%d", i), i, nil))
+	}
+	assert.Equal(t, int64(2), cache.Len())
+}
+
+func TestCacheMaxBytes(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxBytes: 1024 * 1024})
+	value := strings.Repeat("x", 1000)
+	for i := 0; i < 1000; i++ {
+		setErr := cache.Set(fmt.Sprintf("t-%d", i), value, nil)
+		assert.Nil(t, setErr)
+		assert.LessOrEqual(t, cache.Bytes(), int64(1024*1024))
+	}
+	assert.Less(t, cache.Len(), int64(1000))
+
+	// usage follows overwrites and deletes
+	cache = NewCacheWithConfig(Config{MaxBytes: 1024 * 1024})
+	assert.Nil(t, cache.Set("t-1", "a", nil))
+	small := cache.Bytes()
+	assert.Nil(t, cache.Set("t-1", "abc", nil))
+	assert.Equal(t, small+2, cache.Bytes())
+	cache.Del("t-1")
+	assert.Equal(t, int64(0), cache.Bytes())
+
+	// the budget is shared, an entry may be larger than an even share of it
+	assert.Nil(t, cache.Set("t-2", strings.Repeat("x", 5000), nil))
+	assert.Equal(t, int64(1), cache.Len())
+	setErr := cache.Set("t-3", strings.Repeat("x", 1024*1024), nil)
+	assert.ErrorIs(t, setErr, ErrTooLarge)
+	assert.Equal(t, int64(1), cache.Len())
+
+	// a small budget still holds entries, evicting from other shards
+	cache = NewCacheWithConfig(Config{MaxBytes: 1000})
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, cache.Set(fmt.Sprintf("t-%d", i), strings.Repeat("x", 300), nil))
+		assert.LessOrEqual(t, cache.Bytes(), int64(1000))
+	}
+	assert.Equal(t, int64(2), cache.Len())
+	var str string
+	assert.Nil(t, cache.Get("t-99", &str))
+}
+
+func TestCacheClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		cache := NewCache()
+		assert.Nil(t, cache.Set("t-1", "a", nil))
+		assert.Nil(t, cache.Close())
+		assert.Nil(t, cache.Close())
+
+		assert.Equal(t, int64(0), cache.Len())
+		assert.Equal(t, int64(0), cache.Bytes())
+		var str string
+		assert.ErrorIs(t, cache.Get("t-1", &str), ErrClosed)
+		assert.ErrorIs(t, cache.Set("t-1", "b", nil), ErrClosed)
+		cache.Del("t-1")
+	}
+
+	// the expirers are gone
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
+
+func TestCacheContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cache := NewCacheWithConfig(Config{Context: ctx})
+	assert.Nil(t, cache.Set("t-1", "a", nil))
+	cancel()
+
+	assert.Eventually(t, func() bool {
+		return errors.Is(cache.Set("t-1", "a", nil), ErrClosed)
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(0), cache.Len())
+}
+
+func TestCacheErrors(t *testing.T) {
+	cache := NewCache(WithMaxBytes(256 * 1024))
+	defer cache.Close()
+
+	var str string
+	getErr := cache.Get("t-1", &str)
+	assert.ErrorIs(t, getErr, ErrNotFound)
+	assert.NotErrorIs(t, getErr, ErrExpired)
+
+	past := -2 * time.Second
+	assert.Nil(t, cache.Set("t-1", "a", &past))
+	getErr = cache.Get("t-1", &str)
+	assert.ErrorIs(t, getErr, ErrExpired)
+	assert.ErrorIs(t, getErr, ErrNotFound)
+
+	var serErr *SerializationError
+	setErr := cache.Set("t-2", make(chan int), nil)
+	assert.ErrorAs(t, setErr, &serErr)
+	assert.Equal(t, "marshal", serErr.Op)
+
+	assert.Nil(t, cache.Set("t-2", "a", nil))
+	var n int
+	getErr = cache.Get("t-2", &n)
+	assert.ErrorAs(t, getErr, &serErr)
+	assert.Equal(t, "unmarshal", serErr.Op)
+	assert.NotErrorIs(t, getErr, ErrNotFound)
+
+	setErr = cache.Set("t-3", strings.Repeat("x", 256*1024), nil)
+	assert.ErrorIs(t, setErr, ErrTooLarge)
+
+	// a miss does not allocate
+	allocs := testing.AllocsPerRun(100, func() {
+		cache.Get("t-4", &str)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
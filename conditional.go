@@ -0,0 +1,52 @@
+package fastlocalcache
+
+import "time"
+
+// SetNX stores value under key only if the key is missing or expired, and
+// reports whether it did.
+func (c *Cache) SetNX(key string, value any, expiration *time.Duration) (bool, error) {
+	return c.setIf(key, value, expiration, false)
+}
+
+// Add stores value under key only if the key is missing or expired,
+// otherwise it returns ErrExists.
+func (c *Cache) Add(key string, value any, expiration *time.Duration) error {
+	stored, err := c.setIf(key, value, expiration, false)
+	if err == nil && !stored {
+		return ErrExists
+	}
+	return err
+}
+
+// Replace stores value under key only if the key is present and not expired,
+// otherwise it returns ErrNotFound.
+func (c *Cache) Replace(key string, value any, expiration *time.Duration) error {
+	stored, err := c.setIf(key, value, expiration, true)
+	if err == nil && !stored {
+		return ErrNotFound
+	}
+	return err
+}
+
+// setIf stores value under key if the liveness of key is live.
+func (c *Cache) setIf(key string, value any, expiration *time.Duration, live bool) (bool, error) {
+	if c.isClosed() {
+		return false, ErrClosed
+	}
+	bs, err := c.serializer.Marshal(value)
+	if err != nil {
+		return false, &SerializationError{Op: "marshal", Err: err}
+	}
+	ci := c.newItem(bs, expiration)
+	s := c.shardedMap.getShard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// an expired item the expirer has not removed yet counts as missing
+	if (s.liveVersionLocked(key, c.clock.Now().Unix()) != 0) != live {
+		return false, nil
+	}
+	if err := s.setLocked(key, ci); err != nil {
+		return false, err
+	}
+	return true, nil
+}
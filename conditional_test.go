@@ -0,0 +1,70 @@
+package fastlocalcache
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheSetNX(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithClock(clock))
+	defer cache.Close()
+
+	var stored int32
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			ok, err := cache.SetNX("lock", g, nil)
+			assert.Nil(t, err)
+			if ok {
+				atomic.AddInt32(&stored, 1)
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), stored)
+
+	// expired keys count as missing even before they are removed
+	ttl := time.Minute
+	assert.Nil(t, cache.Set("t-1", "a", &ttl))
+	ok, err := cache.SetNX("t-1", "b", nil)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	clock.Add(ttl + time.Second)
+	ok, err = cache.SetNX("t-1", "c", nil)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	var str string
+	assert.Nil(t, cache.Get("t-1", &str))
+	assert.Equal(t, "c", str)
+}
+
+func TestCacheAddReplace(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithClock(clock))
+	defer cache.Close()
+
+	var str string
+	assert.ErrorIs(t, cache.Replace("t-1", "a", nil), ErrNotFound)
+	assert.ErrorIs(t, cache.Get("t-1", &str), ErrNotFound)
+
+	ttl := time.Minute
+	assert.Nil(t, cache.Add("t-1", "a", &ttl))
+	assert.ErrorIs(t, cache.Add("t-1", "b", nil), ErrExists)
+	assert.Nil(t, cache.Replace("t-1", "c", &ttl))
+	assert.Nil(t, cache.Get("t-1", &str))
+	assert.Equal(t, "c", str)
+
+	clock.Add(ttl + time.Second)
+	assert.ErrorIs(t, cache.Replace("t-1", "d", nil), ErrNotFound)
+	assert.Nil(t, cache.Add("t-1", "e", nil))
+	assert.Nil(t, cache.Get("t-1", &str))
+	assert.Equal(t, "e", str)
+	assert.Equal(t, int64(1), cache.Len())
+}
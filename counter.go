@@ -0,0 +1,67 @@
+package fastlocalcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// IncrBy adds delta to the counter under key and returns the new count. A
+// missing or expired key is created with a count of delta expiring after
+// expiration, nil meaning never; later increments keep that expiry. A key
+// set with Set is turned into a counter if its value unmarshals into an
+// int64, otherwise a *SerializationError is returned. Get reads a counter
+// as an int64.
+func (c *Cache) IncrBy(key string, delta int64, expiration *time.Duration) (int64, error) {
+	if c.isClosed() {
+		return 0, ErrClosed
+	}
+	s := c.shardedMap.getShard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if value, ok := s.items.Load(key); ok {
+		ci := value.(*cacheItem)
+		if !hasExpired(c.clock.Now().Unix(), ci.expireAt) {
+			if ci.counter != nil {
+				n := atomic.AddInt64(ci.counter, delta)
+				// an increment is a use, like the overwrite of setLocked
+				s.access(key, ci)
+				atomic.AddUint64(&s.stats.sets, 1)
+				s.version++
+				atomic.StoreUint64(&ci.version, s.version)
+				if s.log != nil {
+					s.log.set(key, ci)
+				}
+				return n, nil
+			}
+			var n int64
+			if err := c.unmarshal(ci.value, &n); err != nil {
+				return 0, err
+			}
+			n += delta
+			counter := &cacheItem{
+				counter:   &n,
+				expireAt:  ci.expireAt,
+				refreshAt: ci.refreshAt,
+				tags:      ci.tags,
+			}
+			if err := s.setLocked(key, counter); err != nil {
+				return 0, err
+			}
+			return n, nil
+		}
+	}
+
+	n := delta
+	ci := c.newItem(nil, expiration)
+	ci.counter = &n
+	if err := s.setLocked(key, ci); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// DecrBy subtracts delta from the counter under key, see IncrBy.
+func (c *Cache) DecrBy(key string, delta int64, expiration *time.Duration) (int64, error) {
+	return c.IncrBy(key, -delta, expiration)
+}
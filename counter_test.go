@@ -0,0 +1,118 @@
+package fastlocalcache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheIncrBy(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithClock(clock))
+	defer cache.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				_, err := cache.IncrBy("counter", 2, nil)
+				assert.Nil(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+	n, err := cache.DecrBy("counter", 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1599), n)
+
+	// counters read like any int64
+	var got int64
+	assert.Nil(t, cache.Get("counter", &got))
+	assert.Equal(t, int64(1599), got)
+
+	// increments bump the version
+	v1, err := cache.GetWithVersion("counter", &got)
+	assert.Nil(t, err)
+	_, err = cache.IncrBy("counter", 1, nil)
+	assert.Nil(t, err)
+	_, err = cache.CompareAndSet("counter", 0, v1, nil)
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+}
+
+func TestCacheIncrByExpiration(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithClock(clock))
+	defer cache.Close()
+
+	// the first increment sets the expiry, later ones keep it
+	ttl := time.Minute
+	n, err := cache.IncrBy("t-1", 1, &ttl)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+	clock.Add(30 * time.Second)
+	longer := time.Hour
+	n, err = cache.IncrBy("t-1", 1, &longer)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+	clock.Add(31 * time.Second)
+	assert.ErrorIs(t, cache.Get("t-1", &n), ErrExpired)
+
+	// an expired counter starts over
+	n, err = cache.IncrBy("t-1", 5, &ttl)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), n)
+}
+
+func TestCacheIncrByValue(t *testing.T) {
+	cache := NewCache()
+	defer cache.Close()
+
+	// an integer value is turned into a counter
+	assert.Nil(t, cache.Set("t-1", 41, nil))
+	n, err := cache.IncrBy("t-1", 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), n)
+	assert.Equal(t, int64(1), cache.Len())
+
+	// and Set turns it back into a value
+	assert.Nil(t, cache.Set("t-1", "a", nil))
+	var str string
+	assert.Nil(t, cache.Get("t-1", &str))
+	assert.Equal(t, "a", str)
+
+	_, err = cache.IncrBy("t-1", 1, nil)
+	var serr *SerializationError
+	assert.True(t, errors.As(err, &serr))
+	assert.Nil(t, cache.Get("t-1", &str))
+	assert.Equal(t, "a", str)
+}
+
+func TestCacheIncrByEviction(t *testing.T) {
+	for name, policy := range map[string]PolicyFactory{
+		"lru":    NewLRUPolicy,
+		"sieve":  NewSIEVEPolicy,
+		"s3fifo": NewS3FIFOPolicy,
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache := NewCache(WithShards(1), WithMaxEntries(3), WithPolicy(policy))
+			defer cache.Close()
+
+			// a counter only incremented is used as much as a key read
+			var str string
+			for i := 1; i <= 12; i++ {
+				n, err := cache.IncrBy("counter", 1, nil)
+				assert.Nil(t, err)
+				assert.Equal(t, int64(i), n)
+				key := fmt.Sprintf("t-%d", i)
+				assert.Nil(t, cache.Set(key, "a", nil))
+				cache.Get(key, &str)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package fastlocalcache
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is returned by Get when the key is missing.
+	ErrNotFound = errors.New("key not found")
+	// ErrExpired is returned by Get when the key exists but has expired, it
+	// matches ErrNotFound too, so errors.Is(err, ErrNotFound) covers both
+	// kinds of misses.
+	ErrExpired = fmt.Errorf("key expired: %w", ErrNotFound)
+	// ErrClosed is returned by the operations of a closed cache.
+	ErrClosed = errors.New("cache closed")
+	// ErrTooLarge is returned by Set when an entry is larger than
+	// Config.MaxBytes.
+	ErrTooLarge = errors.New("entry too large")
+	// ErrVersionMismatch is returned by CompareAndSet when the key was
+	// written since the expected version was read.
+	ErrVersionMismatch = errors.New("version mismatch")
+	// ErrExists is returned by Add when the key is present and not expired.
+	ErrExists = errors.New("key exists")
+	// ErrCorruptSnapshot is returned by LoadFrom when the snapshot is
+	// truncated, fails its checksum or has an unknown format.
+	ErrCorruptSnapshot = errors.New("corrupt snapshot")
+)
+
+// SerializationError is returned when the Serializer fails to marshal or
+// unmarshal a value.
+type SerializationError struct {
+	Op  string // "marshal" or "unmarshal"
+	Err error
+}
+
+func (e *SerializationError) Error() string {
+	return e.Op + " error: " + e.Err.Error()
+}
+
+func (e *SerializationError) Unwrap() error {
+	return e.Err
+}
@@ -1,6 +1,6 @@
 module fastlocalcache
 
-go 1.19
+go 1.24
 
 require (
 	github.com/VictoriaMetrics/fastcache v1.12.1
@@ -0,0 +1,116 @@
+package fastlocalcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Loader loads the value of a key missing from the cache.
+type Loader func(ctx context.Context, key string) (any, error)
+
+// GetOrLoad gets the value of key like Get, on a miss it calls loader, stores
+// the result with the given expiration and unmarshals it into value.
+// Concurrent misses of the same key share a single loader call and all get
+// its error. The loader runs with the values of ctx but is not cancelled with
+// it, so a waiter whose ctx is done returns ctx.Err() without affecting the
+// others. The loaded value is returned even if it cannot be stored.
+//
+// With Config.RefreshAfter, an entry older than that is returned while it is
+// reloaded in the background. With Config.StaleWhileRevalidate, an entry
+// expired for less than that is reloaded and returned if the loader fails.
+func (c *Cache) GetOrLoad(ctx context.Context, key string, value any, loader Loader, expiration *time.Duration) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	now := c.clock.Now().Unix()
+	ci, err := c.getItem(key, now)
+	if err == nil {
+		if ci.refreshAt != 0 && now >= ci.refreshAt {
+			c.load(ctx, key, loader, expiration)
+		}
+		return c.unmarshalItem(ci, value)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	call := c.load(ctx, key, loader, expiration)
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if call.err != nil {
+		if ci != nil {
+			// stale while revalidate
+			return c.unmarshalItem(ci, value)
+		}
+		return call.err
+	}
+	return c.unmarshal(call.value, value)
+}
+
+// load starts loading key unless it is being loaded already.
+func (c *Cache) load(ctx context.Context, key string, loader Loader, expiration *time.Duration) *loadCall {
+	return c.loads.do(key, func() ([]byte, error) {
+		loaded, err := loader(context.WithoutCancel(ctx), key)
+		if err != nil {
+			return nil, err
+		}
+		bs, err := c.serializer.Marshal(loaded)
+		if err != nil {
+			return nil, &SerializationError{Op: "marshal", Err: err}
+		}
+		// fails with ErrClosed once the cache is closed, the value is still
+		// returned to the callers waiting for it
+		c.setBytes(key, bs, expiration)
+		return bs, nil
+	})
+}
+
+// loadCall is a loader call in flight, value and err are set once done is
+// closed.
+type loadCall struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// loadGroup deduplicates concurrent loads of the same key.
+type loadGroup struct {
+	mu    sync.Mutex
+	calls map[string]*loadCall
+}
+
+// do starts fn in the background unless a call for key is in flight already,
+// and returns the call to wait for.
+func (g *loadGroup) do(key string, fn func() ([]byte, error)) *loadCall {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		return call
+	}
+	if g.calls == nil {
+		g.calls = make(map[string]*loadCall)
+	}
+	call := &loadCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				call.value, call.err = nil, fmt.Errorf("loader panic: %v", r)
+			}
+			g.mu.Lock()
+			delete(g.calls, key)
+			g.mu.Unlock()
+			close(call.done)
+		}()
+		call.value, call.err = fn()
+	}()
+	return call
+}
@@ -0,0 +1,207 @@
+package fastlocalcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheGetOrLoad(t *testing.T) {
+	cache := NewCache()
+	defer cache.Close()
+
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (any, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "loaded-" + key, nil
+	}
+
+	// concurrent misses share one loader call
+	var wg sync.WaitGroup
+	results := make([]string, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.Nil(t, cache.GetOrLoad(context.Background(), "t-1", &results[i], loader, nil))
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, result := range results {
+		assert.Equal(t, "loaded-t-1", result)
+	}
+
+	// the loaded value is cached
+	var str string
+	assert.Nil(t, cache.Get("t-1", &str))
+	assert.Nil(t, cache.GetOrLoad(context.Background(), "t-1", &str, loader, nil))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestCacheGetOrLoadError(t *testing.T) {
+	cache := NewCache()
+	defer cache.Close()
+
+	loadErr := errors.New("database down")
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (any, error) {
+		<-release
+		return nil, loadErr
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var str string
+			assert.ErrorIs(t, cache.GetOrLoad(context.Background(), "t-1", &str, loader, nil), loadErr)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int64(0), cache.Len())
+
+	// a panicking loader fails the waiters instead of hanging them
+	var str string
+	getErr := cache.GetOrLoad(context.Background(), "t-2", &str, func(ctx context.Context, key string) (any, error) {
+		panic("boom")
+	}, nil)
+	assert.ErrorContains(t, getErr, "boom")
+}
+
+func TestCacheGetOrLoadCancel(t *testing.T) {
+	cache := NewCache()
+	defer cache.Close()
+
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (any, error) {
+		<-release
+		// the loader is not cancelled with the waiter that started it
+		return "loaded", ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error)
+	go func() {
+		var str string
+		cancelled <- cache.GetOrLoad(ctx, "t-1", &str, loader, nil)
+	}()
+	loaded := make(chan string)
+	go func() {
+		var str string
+		assert.Nil(t, cache.GetOrLoad(context.Background(), "t-1", &str, loader, nil))
+		loaded <- str
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-cancelled, context.Canceled)
+	close(release)
+	assert.Equal(t, "loaded", <-loaded)
+}
+
+func TestCacheGetOrLoadAfterClose(t *testing.T) {
+	cache := NewCache()
+
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (any, error) {
+		<-release
+		return "loaded", nil
+	}
+	loaded := make(chan string)
+	go func() {
+		var str string
+		assert.Nil(t, cache.GetOrLoad(context.Background(), "t-1", &str, loader, nil))
+		loaded <- str
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Nil(t, cache.Close())
+	close(release)
+	// the waiter gets the value, the closed cache does not store it
+	assert.Equal(t, "loaded", <-loaded)
+	assert.Equal(t, int64(0), cache.Len())
+	assert.Equal(t, int64(0), cache.Bytes())
+}
+
+func TestCacheRefreshAhead(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithClock(clock), WithRefreshAfter(time.Minute))
+	defer cache.Close()
+
+	var version int32
+	refreshed := make(chan struct{}, 1)
+	loader := func(ctx context.Context, key string) (any, error) {
+		v := atomic.AddInt32(&version, 1)
+		if v > 1 {
+			refreshed <- struct{}{}
+		}
+		return v, nil
+	}
+	ttl := time.Hour
+	var v int32
+	assert.Nil(t, cache.GetOrLoad(context.Background(), "t-1", &v, loader, &ttl))
+	assert.Equal(t, int32(1), v)
+
+	// past the refresh age the current value is served while reloading
+	clock.Add(2 * time.Minute)
+	assert.Nil(t, cache.GetOrLoad(context.Background(), "t-1", &v, loader, &ttl))
+	assert.Equal(t, int32(1), v)
+	<-refreshed
+	assert.Eventually(t, func() bool {
+		return cache.Get("t-1", &v) == nil && v == 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithClock(clock), WithStaleWhileRevalidate(time.Minute))
+	defer cache.Close()
+
+	ttl := time.Minute
+	assert.Nil(t, cache.Set("t-1", "stale", &ttl))
+	clock.Add(ttl + 2*time.Second)
+
+	// expired entries are misses for Get
+	var str string
+	assert.ErrorIs(t, cache.Get("t-1", &str), ErrExpired)
+
+	// a failing loader falls back to the stale value
+	failing := func(ctx context.Context, key string) (any, error) {
+		return nil, errors.New("database down")
+	}
+	assert.Nil(t, cache.GetOrLoad(context.Background(), "t-1", &str, failing, &ttl))
+	assert.Equal(t, "stale", str)
+
+	// a working loader replaces it
+	working := func(ctx context.Context, key string) (any, error) {
+		return "fresh", nil
+	}
+	assert.Nil(t, cache.GetOrLoad(context.Background(), "t-1", &str, working, &ttl))
+	assert.Equal(t, "fresh", str)
+
+	// past the stale window the entry is gone
+	clock.Add(ttl + time.Minute + 2*time.Second)
+	assert.EqualError(t, cache.GetOrLoad(context.Background(), "t-1", &str, failing, &ttl), "database down")
+	assert.Equal(t, int64(0), cache.Len())
+
+	// the expirer keeps entries during the stale window
+	assert.Nil(t, cache.Set("t-2", "stale", &ttl))
+	clock.Add(ttl + 2*time.Second)
+	cache.expire()
+	assert.Equal(t, int64(1), cache.Len())
+	clock.Add(time.Minute)
+	cache.expire()
+	assert.Equal(t, int64(0), cache.Len())
+}
@@ -0,0 +1,108 @@
+package fastlocalcache
+
+import (
+	"context"
+	"time"
+)
+
+// Option configures a Cache created by NewCache.
+type Option func(config *Config)
+
+// WithSerializer sets how values are encoded, JSONSerializer by default.
+func WithSerializer(serializer Serializer) Option {
+	return func(config *Config) {
+		config.Serializer = serializer
+	}
+}
+
+// WithShards sets the number of shards, 256 by default.
+func WithShards(shards int64) Option {
+	return func(config *Config) {
+		config.Shards = shards
+	}
+}
+
+// WithHashFunc sets the hash used to pick the shard of a key, KeyToHash by
+// default.
+func WithHashFunc(hash func(key string) uint64) Option {
+	return func(config *Config) {
+		config.HashFunc = hash
+	}
+}
+
+// WithCleanupInterval sets how often expired entries are removed in the
+// background, one second by default.
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(config *Config) {
+		config.CleanupInterval = interval
+	}
+}
+
+// WithClock sets the source of the current time, the system clock by default.
+func WithClock(clock Clock) Option {
+	return func(config *Config) {
+		config.Clock = clock
+	}
+}
+
+// WithMaxEntries limits the number of entries, see Config.MaxEntries.
+func WithMaxEntries(maxEntries int64) Option {
+	return func(config *Config) {
+		config.MaxEntries = maxEntries
+	}
+}
+
+// WithMaxBytes limits the memory used by entries, see Config.MaxBytes.
+func WithMaxBytes(maxBytes int64) Option {
+	return func(config *Config) {
+		config.MaxBytes = maxBytes
+	}
+}
+
+// WithPolicy sets the eviction policy, NewLRUPolicy by default.
+func WithPolicy(policy PolicyFactory) Option {
+	return func(config *Config) {
+		config.Policy = policy
+	}
+}
+
+// WithRefreshAfter makes GetOrLoad reload entries older than d in the
+// background while still returning them, see Config.RefreshAfter.
+func WithRefreshAfter(d time.Duration) Option {
+	return func(config *Config) {
+		config.RefreshAfter = d
+	}
+}
+
+// WithStaleWhileRevalidate keeps expired entries for d so that GetOrLoad can
+// return them when its loader fails, see Config.StaleWhileRevalidate.
+func WithStaleWhileRevalidate(d time.Duration) Option {
+	return func(config *Config) {
+		config.StaleWhileRevalidate = d
+	}
+}
+
+// WithPrefixIndex indexes the keys by prefix, see Config.PrefixIndex.
+func WithPrefixIndex() Option {
+	return func(config *Config) {
+		config.PrefixIndex = true
+	}
+}
+
+// WithContext closes the cache when ctx is done.
+func WithContext(ctx context.Context) Option {
+	return func(config *Config) {
+		config.Context = ctx
+	}
+}
+
+// Clock tells the current time, it lets tests control expiration.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
@@ -0,0 +1,86 @@
+package fastlocalcache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1_000_000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type countingSerializer struct {
+	JSONSerializer
+	marshaled int
+}
+
+func (s *countingSerializer) Marshal(v any) ([]byte, error) {
+	s.marshaled++
+	return s.JSONSerializer.Marshal(v)
+}
+
+func TestNewCacheOptions(t *testing.T) {
+	clock := newFakeClock()
+	serializer := &countingSerializer{}
+	hashed := 0
+	cache := NewCache(
+		WithSerializer(serializer),
+		WithShards(4),
+		WithHashFunc(func(key string) uint64 {
+			hashed++
+			return KeyToHash(key)
+		}),
+		WithCleanupInterval(time.Millisecond),
+		WithClock(clock),
+		WithMaxEntries(8),
+		WithPolicy(NewFIFOPolicy),
+	)
+	defer cache.Close()
+	assert.Len(t, cache.shardedMap.shards, 4)
+
+	ttl := time.Minute
+	for i := 0; i < 16; i++ {
+		assert.Nil(t, cache.Set(string(rune('a'+i)), i, &ttl))
+	}
+	assert.Equal(t, int64(8), cache.Len())
+	assert.Equal(t, 16, serializer.marshaled)
+	assert.Equal(t, 16, hashed)
+
+	// the expirer follows the clock
+	clock.Add(ttl + time.Second)
+	assert.Eventually(t, func() bool {
+		return cache.Len() == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestNewCacheDefaults(t *testing.T) {
+	cache := NewCache()
+	defer cache.Close()
+	assert.Len(t, cache.shardedMap.shards, int(shardsCount))
+	assert.IsType(t, JSONSerializer{}, cache.serializer)
+	assert.Nil(t, cache.shardedMap.shards[0].policy)
+
+	cache = NewCache(WithMaxBytes(1 << 20))
+	defer cache.Close()
+	assert.IsType(t, &lruPolicy{}, cache.shardedMap.shards[0].policy)
+}
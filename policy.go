@@ -0,0 +1,260 @@
+package fastlocalcache
+
+import (
+	"container/heap"
+	"container/list"
+	"sync"
+)
+
+// EvictionPolicy chooses the entries a shard evicts when it is full. Every
+// shard owns a policy of its own. Add, Remove and Evict are called with the
+// shard lock held, Access is called on hits without it, so implementations
+// must be safe for concurrent use.
+type EvictionPolicy interface {
+	// Add records a newly inserted key.
+	Add(key string)
+	// Access records a hit or an overwrite of key.
+	Access(key string)
+	// Remove forgets a key deleted or expired by the cache.
+	Remove(key string)
+	// Evict forgets and returns the next key to evict.
+	Evict() (string, bool)
+}
+
+// defaultPolicyCapacity sizes the bookkeeping of policies that depend on the
+// capacity when only the bytes of a shard are limited.
+const defaultPolicyCapacity = 1024
+
+// PolicyFactory creates the policy of a shard, capacity is the maximum number
+// of entries of that shard or 0 if only its bytes are limited.
+type PolicyFactory func(capacity int64) EvictionPolicy
+
+// NewLRUPolicy evicts the least recently used key.
+func NewLRUPolicy(capacity int64) EvictionPolicy {
+	return &lruPolicy{list: newLRUList()}
+}
+
+type lruPolicy struct {
+	mu   sync.Mutex
+	list *lruList
+}
+
+func (p *lruPolicy) Add(key string) {
+	p.mu.Lock()
+	p.list.add(key)
+	p.mu.Unlock()
+}
+
+func (p *lruPolicy) Access(key string) {
+	p.mu.Lock()
+	p.list.touch(key)
+	p.mu.Unlock()
+}
+
+func (p *lruPolicy) Remove(key string) {
+	p.mu.Lock()
+	p.list.remove(key)
+	p.mu.Unlock()
+}
+
+func (p *lruPolicy) Evict() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.list.evict()
+}
+
+// NewFIFOPolicy evicts the earliest inserted key, hits are ignored.
+func NewFIFOPolicy(capacity int64) EvictionPolicy {
+	return &fifoPolicy{list: newLRUList()}
+}
+
+type fifoPolicy struct {
+	mu   sync.Mutex
+	list *lruList
+}
+
+func (p *fifoPolicy) Add(key string) {
+	p.mu.Lock()
+	p.list.add(key)
+	p.mu.Unlock()
+}
+
+func (p *fifoPolicy) Access(key string) {}
+
+func (p *fifoPolicy) Remove(key string) {
+	p.mu.Lock()
+	p.list.remove(key)
+	p.mu.Unlock()
+}
+
+func (p *fifoPolicy) Evict() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.list.evict()
+}
+
+// NewLFUPolicy evicts the least frequently used key, ties are broken by
+// evicting the least recently used one.
+func NewLFUPolicy(capacity int64) EvictionPolicy {
+	return &lfuPolicy{entries: make(map[string]*lfuEntry)}
+}
+
+type lfuPolicy struct {
+	mu      sync.Mutex
+	heap    lfuHeap
+	entries map[string]*lfuEntry
+	tick    uint64
+}
+
+type lfuEntry struct {
+	key   string
+	freq  uint64
+	tick  uint64 // last access
+	index int
+}
+
+func (p *lfuPolicy) Add(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tick++
+	if e, ok := p.entries[key]; ok {
+		e.freq++
+		e.tick = p.tick
+		heap.Fix(&p.heap, e.index)
+		return
+	}
+	e := &lfuEntry{key: key, freq: 1, tick: p.tick}
+	p.entries[key] = e
+	heap.Push(&p.heap, e)
+}
+
+func (p *lfuPolicy) Access(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.entries[key]; ok {
+		p.tick++
+		e.freq++
+		e.tick = p.tick
+		heap.Fix(&p.heap, e.index)
+	}
+}
+
+func (p *lfuPolicy) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.entries[key]; ok {
+		heap.Remove(&p.heap, e.index)
+		delete(p.entries, key)
+	}
+}
+
+func (p *lfuPolicy) Evict() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.heap) == 0 {
+		return "", false
+	}
+	e := heap.Pop(&p.heap).(*lfuEntry)
+	delete(p.entries, e.key)
+	return e.key, true
+}
+
+type lfuHeap []*lfuEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x any) {
+	e := x.(*lfuEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
+
+// lruList tracks keys from the most recently used to the least recently used.
+// It is not safe for concurrent use.
+type lruList struct {
+	ll       *list.List
+	elements map[string]*list.Element
+}
+
+func newLRUList() *lruList {
+	return &lruList{
+		ll:       list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+func (l *lruList) len() int {
+	return l.ll.Len()
+}
+
+func (l *lruList) contains(key string) bool {
+	_, ok := l.elements[key]
+	return ok
+}
+
+func (l *lruList) add(key string) {
+	if e, ok := l.elements[key]; ok {
+		l.ll.MoveToFront(e)
+		return
+	}
+	l.elements[key] = l.ll.PushFront(key)
+}
+
+func (l *lruList) touch(key string) bool {
+	e, ok := l.elements[key]
+	if ok {
+		l.ll.MoveToFront(e)
+	}
+	return ok
+}
+
+func (l *lruList) remove(key string) bool {
+	e, ok := l.elements[key]
+	if ok {
+		l.ll.Remove(e)
+		delete(l.elements, key)
+	}
+	return ok
+}
+
+// back returns the least recently used key without removing it.
+func (l *lruList) back() (string, bool) {
+	e := l.ll.Back()
+	if e == nil {
+		return "", false
+	}
+	return e.Value.(string), true
+}
+
+// evict removes and returns the least recently used key.
+func (l *lruList) evict() (string, bool) {
+	e := l.ll.Back()
+	if e == nil {
+		return "", false
+	}
+	key := l.ll.Remove(e).(string)
+	delete(l.elements, key)
+	return key, true
+}
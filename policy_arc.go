@@ -0,0 +1,110 @@
+package fastlocalcache
+
+import "sync"
+
+// NewARCPolicy implements the Adaptive Replacement Cache: keys seen once live
+// in T1, keys seen again in T2, and the ghost lists B1 and B2 remember the
+// keys recently evicted from each. A miss found in a ghost list shifts the
+// target size of T1 towards recency or frequency, so the policy tunes itself
+// between scans and hot sets.
+func NewARCPolicy(capacity int64) EvictionPolicy {
+	if capacity <= 0 {
+		capacity = defaultPolicyCapacity
+	}
+	return &arcPolicy{
+		capacity: int(capacity),
+		t1:       newLRUList(),
+		t2:       newLRUList(),
+		b1:       newLRUList(),
+		b2:       newLRUList(),
+	}
+}
+
+type arcPolicy struct {
+	mu       sync.Mutex
+	capacity int
+	p        int // target size of t1
+	t1       *lruList
+	t2       *lruList
+	b1       *lruList
+	b2       *lruList
+}
+
+func (a *arcPolicy) Add(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.t1.contains(key) || a.t2.contains(key) {
+		a.accessLocked(key)
+		return
+	}
+	switch {
+	case a.b1.remove(key):
+		// recently evicted from t1: recency deserves more room
+		a.p = minInt(a.capacity, a.p+maxInt(a.b2.len()/maxInt(a.b1.len(), 1), 1))
+		a.t2.add(key)
+	case a.b2.remove(key):
+		// recently evicted from t2: frequency deserves more room
+		a.p = maxInt(0, a.p-maxInt(a.b1.len()/maxInt(a.b2.len(), 1), 1))
+		a.t2.add(key)
+	default:
+		a.t1.add(key)
+	}
+}
+
+func (a *arcPolicy) Access(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.accessLocked(key)
+}
+
+func (a *arcPolicy) accessLocked(key string) {
+	if a.t1.remove(key) {
+		a.t2.add(key)
+		return
+	}
+	a.t2.touch(key)
+}
+
+func (a *arcPolicy) Remove(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.t1.remove(key) {
+		a.t2.remove(key)
+	}
+}
+
+func (a *arcPolicy) Evict() (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.t1.len() > 0 && (a.t1.len() > a.p || a.t2.len() == 0) {
+		key, _ := a.t1.evict()
+		a.b1.add(key)
+		if a.t1.len()+a.b1.len() > a.capacity {
+			a.b1.evict()
+		}
+		return key, true
+	}
+	key, ok := a.t2.evict()
+	if !ok {
+		return "", false
+	}
+	a.b2.add(key)
+	if a.t1.len()+a.t2.len()+a.b1.len()+a.b2.len() > 2*a.capacity {
+		a.b2.evict()
+	}
+	return key, true
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,146 @@
+package fastlocalcache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// s3FIFOSmallPercent is the share of the capacity given to the small queue.
+	s3FIFOSmallPercent = 10
+	// s3FIFOMaxHits caps the hit counter of a key.
+	s3FIFOMaxHits = 3
+)
+
+// NewS3FIFOPolicy implements S3-FIFO: new keys enter a small FIFO queue and are
+// evicted quickly unless they were hit while there, in which case they move to
+// the main FIFO queue that reinserts keys while they have hits left. Keys
+// evicted from the small queue are remembered by a ghost queue and go straight
+// to the main queue when inserted again. The cache keeps the node of a key in
+// its entry, so a hit only bumps its counter, without locking the policy.
+func NewS3FIFOPolicy(capacity int64) EvictionPolicy {
+	if capacity <= 0 {
+		capacity = defaultPolicyCapacity
+	}
+	smallCap := capacity * s3FIFOSmallPercent / 100
+	if smallCap < 1 {
+		smallCap = 1
+	}
+	return &s3FIFOPolicy{
+		small:    list.New(),
+		main:     list.New(),
+		ghost:    newLRUList(),
+		elements: make(map[string]*list.Element),
+		smallCap: int(smallCap),
+		ghostCap: int(capacity - smallCap + 1),
+	}
+}
+
+type s3FIFOPolicy struct {
+	mu       sync.RWMutex
+	small    *list.List // newest at front
+	main     *list.List // newest at front
+	ghost    *lruList
+	elements map[string]*list.Element
+	smallCap int
+	ghostCap int
+}
+
+type s3FIFONode struct {
+	visitedNode
+	small bool // which queue holds the node
+}
+
+func (p *s3FIFOPolicy) Add(key string) {
+	p.addVisited(key)
+}
+
+func (p *s3FIFOPolicy) addVisited(key string) *visitedNode {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.elements[key]; ok {
+		node := e.Value.(*s3FIFONode)
+		node.hit()
+		return &node.visitedNode
+	}
+	node := &s3FIFONode{visitedNode: visitedNode{key: key, max: s3FIFOMaxHits}}
+	if p.ghost.remove(key) {
+		p.elements[key] = p.main.PushFront(node)
+		return &node.visitedNode
+	}
+	node.small = true
+	p.elements[key] = p.small.PushFront(node)
+	return &node.visitedNode
+}
+
+func (p *s3FIFOPolicy) Access(key string) {
+	p.mu.RLock()
+	e, ok := p.elements[key]
+	if ok {
+		e.Value.(*s3FIFONode).hit()
+	}
+	p.mu.RUnlock()
+}
+
+func (p *s3FIFOPolicy) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.elements[key]; ok {
+		if e.Value.(*s3FIFONode).small {
+			p.small.Remove(e)
+		} else {
+			p.main.Remove(e)
+		}
+		delete(p.elements, key)
+	}
+}
+
+func (p *s3FIFOPolicy) Evict() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.small.Len() >= p.smallCap || p.main.Len() == 0 {
+		if key, ok := p.evictSmall(); ok {
+			return key, true
+		}
+	}
+	return p.evictMain()
+}
+
+// evictSmall promotes the keys hit while in the small queue and evicts the
+// first one that was not.
+func (p *s3FIFOPolicy) evictSmall() (string, bool) {
+	for e := p.small.Back(); e != nil; e = p.small.Back() {
+		node := p.small.Remove(e).(*s3FIFONode)
+		if atomic.LoadInt32(&node.hits) > 0 {
+			atomic.StoreInt32(&node.hits, 0)
+			node.small = false
+			p.elements[node.key] = p.main.PushFront(node)
+			continue
+		}
+		delete(p.elements, node.key)
+		p.ghost.add(node.key)
+		for p.ghost.len() > p.ghostCap {
+			p.ghost.evict()
+		}
+		return node.key, true
+	}
+	return "", false
+}
+
+// evictMain reinserts the keys with hits left and evicts the first one
+// without.
+func (p *s3FIFOPolicy) evictMain() (string, bool) {
+	for e := p.main.Back(); e != nil; e = p.main.Back() {
+		node := e.Value.(*s3FIFONode)
+		if hits := atomic.LoadInt32(&node.hits); hits > 0 {
+			atomic.StoreInt32(&node.hits, hits-1)
+			p.main.MoveToFront(e)
+			continue
+		}
+		p.main.Remove(e)
+		delete(p.elements, node.key)
+		return node.key, true
+	}
+	return "", false
+}
@@ -0,0 +1,117 @@
+package fastlocalcache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// NewSIEVEPolicy implements SIEVE: keys are kept in insertion order and a hand
+// sweeps from the oldest to the newest, sparing and unmarking visited keys and
+// evicting the first unvisited one. The cache keeps the node of a key in its
+// entry, so a hit only marks it as visited, without locking the policy.
+func NewSIEVEPolicy(capacity int64) EvictionPolicy {
+	return &sievePolicy{
+		ll:       list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+type sievePolicy struct {
+	mu       sync.RWMutex
+	ll       *list.List // newest at front
+	elements map[string]*list.Element
+	hand     *list.Element
+}
+
+// visitedNode is a key of a FIFO queue with a small saturating hit counter.
+type visitedNode struct {
+	key  string
+	hits int32
+	max  int32
+}
+
+// hit bumps the counter, a saturated counter is only read so that hot keys
+// do not write to shared memory.
+func (n *visitedNode) hit() {
+	for {
+		hits := atomic.LoadInt32(&n.hits)
+		if hits >= n.max || atomic.CompareAndSwapInt32(&n.hits, hits, hits+1) {
+			return
+		}
+	}
+}
+
+// visitedPolicy is implemented by the policies whose hits only bump the
+// counter of a visitedNode. The shard stores the node in the entry of the key
+// and hits it directly instead of calling Access.
+type visitedPolicy interface {
+	EvictionPolicy
+	// addVisited is Add returning the node of key.
+	addVisited(key string) *visitedNode
+}
+
+func (p *sievePolicy) Add(key string) {
+	p.addVisited(key)
+}
+
+func (p *sievePolicy) addVisited(key string) *visitedNode {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.elements[key]; ok {
+		node := e.Value.(*visitedNode)
+		node.hit()
+		return node
+	}
+	node := &visitedNode{key: key, max: 1}
+	p.elements[key] = p.ll.PushFront(node)
+	return node
+}
+
+func (p *sievePolicy) Access(key string) {
+	p.mu.RLock()
+	e, ok := p.elements[key]
+	if ok {
+		e.Value.(*visitedNode).hit()
+	}
+	p.mu.RUnlock()
+}
+
+func (p *sievePolicy) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.elements[key]; ok {
+		p.removeLocked(e)
+	}
+}
+
+func (p *sievePolicy) removeLocked(e *list.Element) {
+	if p.hand == e {
+		p.hand = e.Prev()
+	}
+	p.ll.Remove(e)
+	delete(p.elements, e.Value.(*visitedNode).key)
+}
+
+func (p *sievePolicy) Evict() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ll.Len() == 0 {
+		return "", false
+	}
+	e := p.hand
+	for {
+		if e == nil {
+			e = p.ll.Back()
+		}
+		node := e.Value.(*visitedNode)
+		if atomic.LoadInt32(&node.hits) == 0 {
+			break
+		}
+		atomic.StoreInt32(&node.hits, 0)
+		e = e.Prev()
+	}
+	p.hand = e
+	p.removeLocked(e)
+	return e.Value.(*visitedNode).key, true
+}
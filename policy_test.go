@@ -0,0 +1,249 @@
+package fastlocalcache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func evictAll(p EvictionPolicy) []string {
+	var keys []string
+	for {
+		key, ok := p.Evict()
+		if !ok {
+			return keys
+		}
+		keys = append(keys, key)
+	}
+}
+
+func TestLRUPolicy(t *testing.T) {
+	p := NewLRUPolicy(3)
+	p.Add("a")
+	p.Add("b")
+	p.Add("c")
+	p.Access("a")
+	p.Remove("b")
+	assert.Equal(t, []string{"c", "a"}, evictAll(p))
+}
+
+func TestFIFOPolicy(t *testing.T) {
+	p := NewFIFOPolicy(3)
+	p.Add("a")
+	p.Add("b")
+	p.Add("c")
+	p.Access("a")
+	p.Remove("b")
+	assert.Equal(t, []string{"a", "c"}, evictAll(p))
+}
+
+func TestLFUPolicy(t *testing.T) {
+	p := NewLFUPolicy(3)
+	p.Add("a")
+	p.Add("b")
+	p.Add("c")
+	p.Access("a")
+	p.Access("a")
+	p.Access("c")
+	p.Access("b")
+	p.Remove("d")
+	assert.Equal(t, []string{"c", "b", "a"}, evictAll(p))
+}
+
+func TestCachePolicy(t *testing.T) {
+	cache := NewCacheWithConfig(Config{MaxEntries: 1, Policy: NewLFUPolicy})
+	assert.Nil(t, cache.Set("t-1", 1, nil))
+	var v int
+	assert.Nil(t, cache.Get("t-1", &v))
+	// the new key is used less than t-1, so it is the victim
+	assert.Nil(t, cache.Set("t-2", 2, nil))
+	assert.Equal(t, int64(1), cache.Len())
+	assert.NotNil(t, cache.Get("t-2", &v))
+	assert.Nil(t, cache.Get("t-1", &v))
+	assert.Equal(t, 1, v)
+
+	for _, policy := range []PolicyFactory{NewLRUPolicy, NewLFUPolicy, NewFIFOPolicy, NewTinyLFUPolicy, NewARCPolicy, NewS3FIFOPolicy, NewSIEVEPolicy} {
+		cache = NewCacheWithConfig(Config{MaxEntries: 512, Policy: policy})
+		for i := 0; i < 2048; i++ {
+			assert.Nil(t, cache.Set(fmt.Sprintf("t-%d", i), i, nil))
+			cache.Get(fmt.Sprintf("t-%d", i/2), &v)
+		}
+		assert.Equal(t, int64(512), cache.Len())
+	}
+}
+
+func TestTinyLFUPolicy(t *testing.T) {
+	p := NewTinyLFUPolicy(100)
+	resident := map[string]bool{}
+	add := func(key string) {
+		if resident[key] {
+			p.Access(key)
+			return
+		}
+		p.Add(key)
+		resident[key] = true
+		for len(resident) > 100 {
+			victim, ok := p.Evict()
+			assert.True(t, ok)
+			assert.True(t, resident[victim])
+			delete(resident, victim)
+		}
+	}
+
+	// make a hot set popular, then scan through many one-hit keys
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 50; i++ {
+			add(fmt.Sprintf("hot-%d", i))
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		add(fmt.Sprintf("scan-%d", i))
+	}
+	for i := 0; i < 50; i++ {
+		assert.True(t, resident[fmt.Sprintf("hot-%d", i)])
+	}
+
+	for key := range resident {
+		p.Remove(key)
+	}
+	assert.Empty(t, evictAll(p))
+}
+
+func TestFrequencySketch(t *testing.T) {
+	s := newFrequencySketch(64)
+	for i := 0; i < 5; i++ {
+		s.increment("a")
+	}
+	s.increment("b")
+	assert.GreaterOrEqual(t, s.estimate("a"), uint8(5))
+	assert.Equal(t, uint8(1), s.estimate("b"))
+	assert.Equal(t, uint8(0), s.estimate("c"))
+
+	// aging halves the counters and clears the doorkeeper
+	s.reset()
+	assert.Equal(t, uint8(2), s.estimate("a"))
+	assert.Equal(t, uint8(0), s.estimate("b"))
+}
+
+func TestARCPolicy(t *testing.T) {
+	p := NewARCPolicy(2).(*arcPolicy)
+	p.Add("a")
+	p.Add("b")
+	p.Access("a")
+	// t1 = [b], t2 = [a]
+	key, ok := p.Evict()
+	assert.True(t, ok)
+	assert.Equal(t, "b", key)
+	assert.True(t, p.b1.contains("b"))
+
+	// a miss in b1 grows the target of t1 and goes straight to t2
+	p.Add("b")
+	assert.Equal(t, 1, p.p)
+	assert.True(t, p.t2.contains("b"))
+	assert.False(t, p.b1.contains("b"))
+
+	p.Remove("a")
+	assert.Equal(t, []string{"b"}, evictAll(p))
+
+	// ghost lists stay bounded
+	p = NewARCPolicy(10).(*arcPolicy)
+	for i := 0; i < 1000; i++ {
+		p.Add(fmt.Sprintf("k-%d", i))
+		if i%3 == 0 {
+			p.Access(fmt.Sprintf("k-%d", i))
+		}
+		if i >= 10 {
+			_, ok := p.Evict()
+			assert.True(t, ok)
+		}
+		assert.LessOrEqual(t, p.t1.len()+p.t2.len()+p.b1.len()+p.b2.len(), 20)
+	}
+}
+
+func TestSIEVEPolicy(t *testing.T) {
+	p := NewSIEVEPolicy(3)
+	p.Add("a")
+	p.Add("b")
+	p.Add("c")
+	p.Access("a")
+	p.Access("c")
+	// the hand spares and unmarks a, then evicts b
+	key, _ := p.Evict()
+	assert.Equal(t, "b", key)
+	p.Add("d")
+	p.Access("d")
+	// the hand resumes after b and unmarks c, d before wrapping around to a
+	key, _ = p.Evict()
+	assert.Equal(t, "a", key)
+	p.Remove("c")
+	assert.Equal(t, []string{"d"}, evictAll(p))
+}
+
+func TestS3FIFOPolicy(t *testing.T) {
+	p := NewS3FIFOPolicy(10).(*s3FIFOPolicy)
+	p.Add("a")
+	p.Add("b")
+	p.Access("a")
+	// a was hit in the small queue and moves to main, b is evicted
+	key, _ := p.Evict()
+	assert.Equal(t, "b", key)
+	assert.True(t, p.ghost.contains("b"))
+
+	// b comes back through the ghost queue straight into main
+	p.Add("b")
+	assert.False(t, p.elements["b"].Value.(*s3FIFONode).small)
+	p.Remove("a")
+	assert.Equal(t, []string{"b"}, evictAll(p))
+}
+
+func TestPolicyConcurrentAccess(t *testing.T) {
+	for _, policy := range []PolicyFactory{NewS3FIFOPolicy, NewSIEVEPolicy} {
+		cache := NewCacheWithConfig(Config{MaxEntries: 256, Policy: policy})
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				var v int
+				for i := 0; i < 2000; i++ {
+					key := fmt.Sprintf("t-%d", (i*(g+1))%700)
+					if cache.Get(key, &v) != nil {
+						cache.Set(key, i, nil)
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		assert.LessOrEqual(t, cache.Len(), int64(256))
+	}
+}
+
+func TestVisitedPolicyHits(t *testing.T) {
+	for _, policy := range []PolicyFactory{NewS3FIFOPolicy, NewSIEVEPolicy} {
+		cache := NewCacheWithConfig(Config{MaxEntries: 3, Shards: 1, Policy: policy})
+		for _, key := range []string{"a", "b", "c"} {
+			assert.Nil(t, cache.Set(key, key, nil))
+		}
+		// the node moves to the new entry on overwrite
+		s := cache.shardedMap.getShard("a")
+		ci, _ := s.items.Load("a")
+		node := ci.(*cacheItem).visited
+		assert.NotNil(t, node)
+		assert.Nil(t, cache.Set("a", "a2", nil))
+		ci, _ = s.items.Load("a")
+		assert.Same(t, node, ci.(*cacheItem).visited)
+
+		// hits go to the node of the entry and spare it
+		atomic.StoreInt32(&node.hits, 0)
+		var str string
+		assert.Nil(t, cache.Get("a", &str))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&node.hits))
+		assert.Nil(t, cache.Set("d", "d", nil))
+		assert.Nil(t, cache.Get("a", &str))
+		assert.ErrorIs(t, cache.Get("b", &str), ErrNotFound)
+		cache.Close()
+	}
+}
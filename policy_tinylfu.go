@@ -0,0 +1,255 @@
+package fastlocalcache
+
+import "sync"
+
+const (
+	// tinyLFUWindowPercent is the share of the capacity given to the window.
+	tinyLFUWindowPercent = 1
+	// tinyLFUProtectedPercent is the share of the main space given to the
+	// protected segment.
+	tinyLFUProtectedPercent = 80
+	// sketchSamplesFactor is the ratio W/C of the TinyLFU paper, the sketch
+	// is aged after W increments.
+	sketchSamplesFactor = 10
+	sketchDepth         = 4
+	sketchMaxCount      = 15
+	// sketchCountersPerEntry widens the rows to make it unlikely that a key
+	// collides with a popular one in every row.
+	sketchCountersPerEntry = 4
+	// doorkeeperBitsPerSample keeps the false positive rate of the doorkeeper
+	// around 5% with two probes.
+	doorkeeperBitsPerSample = 8
+)
+
+// NewTinyLFUPolicy implements Window-TinyLFU: new keys enter a small LRU
+// window, keys leaving the window compete with the victim of the segmented
+// LRU main space and only the more frequent one, as estimated by a count-min
+// sketch and a doorkeeper, is kept.
+func NewTinyLFUPolicy(capacity int64) EvictionPolicy {
+	if capacity <= 0 {
+		capacity = defaultPolicyCapacity
+	}
+	windowCap := capacity * tinyLFUWindowPercent / 100
+	if windowCap < 1 {
+		windowCap = 1
+	}
+	mainCap := capacity - windowCap
+	protectedCap := mainCap * tinyLFUProtectedPercent / 100
+	if protectedCap < 1 {
+		protectedCap = 1
+	}
+	return &tinyLFUPolicy{
+		sketch:       newFrequencySketch(capacity),
+		window:       newLRUList(),
+		probation:    newLRUList(),
+		protected:    newLRUList(),
+		windowCap:    int(windowCap),
+		protectedCap: int(protectedCap),
+	}
+}
+
+type tinyLFUPolicy struct {
+	mu           sync.Mutex
+	sketch       *frequencySketch
+	window       *lruList
+	probation    *lruList
+	protected    *lruList
+	windowCap    int
+	protectedCap int
+	// candidate is the last key moved from the window to probation, it has
+	// to win against the probation victim to stay.
+	candidate    string
+	hasCandidate bool
+}
+
+func (p *tinyLFUPolicy) Add(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sketch.increment(key)
+	if p.window.touch(key) || p.probation.contains(key) || p.protected.contains(key) {
+		p.accessLocked(key)
+		return
+	}
+	p.window.add(key)
+	for p.window.len() > p.windowCap {
+		demoted, _ := p.window.evict()
+		p.probation.add(demoted)
+		p.candidate, p.hasCandidate = demoted, true
+	}
+}
+
+func (p *tinyLFUPolicy) Access(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sketch.increment(key)
+	p.accessLocked(key)
+}
+
+func (p *tinyLFUPolicy) accessLocked(key string) {
+	if p.window.touch(key) || p.protected.touch(key) {
+		return
+	}
+	if !p.probation.remove(key) {
+		return
+	}
+	p.protected.add(key)
+	for p.protected.len() > p.protectedCap {
+		demoted, _ := p.protected.evict()
+		p.probation.add(demoted)
+	}
+}
+
+func (p *tinyLFUPolicy) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.window.remove(key) && !p.probation.remove(key) {
+		p.protected.remove(key)
+	}
+	if p.hasCandidate && p.candidate == key {
+		p.hasCandidate = false
+	}
+}
+
+func (p *tinyLFUPolicy) Evict() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	victim, ok := p.probation.back()
+	if ok && p.hasCandidate && p.candidate != victim && p.probation.contains(p.candidate) {
+		// admission: the less frequent of the two leaves the cache
+		if p.sketch.estimate(p.candidate) <= p.sketch.estimate(victim) {
+			victim = p.candidate
+		}
+	}
+	if p.hasCandidate && p.candidate == victim {
+		p.hasCandidate = false
+	}
+	if ok {
+		p.probation.remove(victim)
+		return victim, true
+	}
+	if victim, ok = p.protected.evict(); ok {
+		return victim, true
+	}
+	return p.window.evict()
+}
+
+// frequencySketch estimates access frequencies with a count-min sketch of
+// 4-bit saturating counters, fronted by a doorkeeper bloom filter that
+// absorbs keys seen only once. Both are halved after enough increments so
+// that old popularity fades.
+type frequencySketch struct {
+	rows           [sketchDepth][]uint8
+	mask           uint64
+	doorkeeper     []uint64
+	doorkeeperMask uint64
+	additions      int64
+	resetAt        int64
+}
+
+func newFrequencySketch(capacity int64) *frequencySketch {
+	width := nextPowerOfTwo(uint64(capacity * sketchCountersPerEntry))
+	bits := nextPowerOfTwo(uint64(capacity * sketchSamplesFactor * doorkeeperBitsPerSample))
+	if bits < 64 {
+		bits = 64
+	}
+	s := &frequencySketch{
+		mask:           width - 1,
+		doorkeeper:     make([]uint64, bits/64),
+		doorkeeperMask: bits - 1,
+		resetAt:        capacity * sketchSamplesFactor,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+var sketchSeeds = [sketchDepth]uint64{
+	0x9e3779b97f4a7c15, 0xbf58476d1ce4e5b9, 0x94d049bb133111eb, 0xc2b2ae3d27d4eb4f,
+}
+
+// mix rehashes h so that the indexes do not correlate with the shard, which
+// was chosen by the same KeyToHash, nor with each other.
+func mix(h, seed uint64) uint64 {
+	h ^= seed
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+func (s *frequencySketch) increment(key string) {
+	h := KeyToHash(key)
+	if s.doorkeeperAdd(h) {
+		for i := range s.rows {
+			idx := mix(h, sketchSeeds[i]) & s.mask
+			if s.rows[i][idx] < sketchMaxCount {
+				s.rows[i][idx]++
+			}
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+func (s *frequencySketch) estimate(key string) uint8 {
+	h := KeyToHash(key)
+	est := uint8(sketchMaxCount)
+	for i := range s.rows {
+		if c := s.rows[i][mix(h, sketchSeeds[i])&s.mask]; c < est {
+			est = c
+		}
+	}
+	if s.doorkeeperContains(h) {
+		est++
+	}
+	return est
+}
+
+// doorkeeperAdd sets the bits of the key and reports whether they were all
+// set already.
+func (s *frequencySketch) doorkeeperAdd(h uint64) bool {
+	found := true
+	for i := 0; i < 2; i++ {
+		bit := mix(h, sketchSeeds[i]) >> 32 & s.doorkeeperMask
+		if s.doorkeeper[bit/64]&(1<<(bit%64)) == 0 {
+			found = false
+			s.doorkeeper[bit/64] |= 1 << (bit % 64)
+		}
+	}
+	return found
+}
+
+func (s *frequencySketch) doorkeeperContains(h uint64) bool {
+	for i := 0; i < 2; i++ {
+		bit := mix(h, sketchSeeds[i]) >> 32 & s.doorkeeperMask
+		if s.doorkeeper[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *frequencySketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	for i := range s.doorkeeper {
+		s.doorkeeper[i] = 0
+	}
+	s.additions /= 2
+}
+
+func nextPowerOfTwo(n uint64) uint64 {
+	p := uint64(1)
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
@@ -0,0 +1,83 @@
+package fastlocalcache
+
+import (
+	"strings"
+	"time"
+)
+
+// ScanPrefix calls fn for each entry whose key starts with prefix and has not
+// expired, with the same guarantees as Range. With Config.PrefixIndex only
+// the matching keys are visited, otherwise every key is.
+func (c *Cache) ScanPrefix(prefix string, fn RangeFunc) {
+	if c.isClosed() {
+		return
+	}
+	now := c.clock.Now().Unix()
+	for _, s := range c.shardedMap.shards {
+		if s.index == nil {
+			completed := c.rangeShard(s, now, func(key string, raw []byte, expireAt time.Time) bool {
+				return !strings.HasPrefix(key, prefix) || fn(key, raw, expireAt)
+			})
+			if !completed {
+				return
+			}
+			continue
+		}
+
+		// fn runs without the lock, entries may change in between
+		for _, key := range s.prefixKeys(prefix) {
+			value, ok := s.items.Load(key)
+			if ok && !c.visit(key, value.(*cacheItem), now, fn) {
+				return
+			}
+		}
+	}
+}
+
+// DeletePrefix deletes the keys starting with prefix, expired or not, and
+// returns how many it deleted. Each shard is locked once.
+func (c *Cache) DeletePrefix(prefix string) int {
+	if c.isClosed() {
+		return 0
+	}
+	deleted := 0
+	for _, s := range c.shardedMap.shards {
+		deleted += s.delPrefix(prefix)
+	}
+	return deleted
+}
+
+func (s *shard) prefixKeys(prefix string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.prefixKeysLocked(prefix)
+}
+
+// prefixKeysLocked returns the keys of s starting with prefix.
+func (s *shard) prefixKeysLocked(prefix string) []string {
+	var keys []string
+	if s.index != nil {
+		s.index.walkPrefix(prefix, func(key string) bool {
+			keys = append(keys, key)
+			return true
+		})
+		return keys
+	}
+	s.items.Range(func(key, _ any) bool {
+		if strings.HasPrefix(key.(string), prefix) {
+			keys = append(keys, key.(string))
+		}
+		return true
+	})
+	return keys
+}
+
+func (s *shard) delPrefix(prefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := s.prefixKeysLocked(prefix)
+	for _, key := range keys {
+		s.delLocked(key)
+	}
+	return len(keys)
+}
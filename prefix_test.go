@@ -0,0 +1,86 @@
+package fastlocalcache
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scanPrefix(cache *Cache, prefix string) []string {
+	var keys []string
+	cache.ScanPrefix(prefix, func(key string, _ []byte, _ time.Time) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+func TestCachePrefix(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"scan":  nil,
+		"index": {WithPrefixIndex()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			clock := newFakeClock()
+			cache := NewCache(append(opts, WithClock(clock))...)
+			defer cache.Close()
+
+			ttl := time.Minute
+			for _, key := range []string{"user:1:name", "user:1:profile", "user:12:name", "user:2:name"} {
+				assert.Nil(t, cache.Set(key, key, nil))
+			}
+			assert.Nil(t, cache.Set("user:1:session", "s", &ttl))
+			assert.Equal(t, []string{"user:1:name", "user:1:profile", "user:1:session"}, scanPrefix(cache, "user:1:"))
+
+			// expired entries are skipped, but deleted
+			clock.Add(ttl + time.Second)
+			assert.Equal(t, []string{"user:1:name", "user:1:profile"}, scanPrefix(cache, "user:1:"))
+			assert.Equal(t, 3, cache.DeletePrefix("user:1:"))
+			assert.Nil(t, scanPrefix(cache, "user:1:"))
+			assert.Equal(t, []string{"user:12:name", "user:2:name"}, scanPrefix(cache, "user:"))
+			assert.Equal(t, int64(2), cache.Len())
+
+			calls := 0
+			cache.ScanPrefix("user:", func(string, []byte, time.Time) bool {
+				calls++
+				return false
+			})
+			assert.Equal(t, 1, calls)
+		})
+	}
+}
+
+func TestCachePrefixIndexConsistency(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithClock(clock), WithPrefixIndex(), WithMaxEntries(100), WithShards(4))
+	defer cache.Close()
+
+	ttl := time.Second
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("t-%d", i%300)
+		switch i % 4 {
+		case 0:
+			cache.Del(key)
+		case 1:
+			assert.Nil(t, cache.Set(key, i, &ttl))
+		default:
+			assert.Nil(t, cache.Set(key, i, nil))
+		}
+	}
+	clock.Add(3 * time.Second)
+	cache.expire()
+
+	// evicted, expired and deleted keys left the index
+	for _, s := range cache.shardedMap.shards {
+		assert.Equal(t, int(s.len), s.index.len)
+		s.index.walkPrefix("", func(key string) bool {
+			_, ok := s.items.Load(key)
+			assert.True(t, ok)
+			return true
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package fastlocalcache
+
+import (
+	"sort"
+	"strings"
+)
+
+// radixTree is a set of keys stored as a compressed prefix tree, it finds the
+// keys with a given prefix without visiting the others. It is not safe for
+// concurrent use.
+type radixTree struct {
+	root radixNode
+	len  int
+}
+
+type radixNode struct {
+	prefix   string       // label of the edge from the parent
+	leaf     bool         // a key ends at this node
+	children []*radixNode // sorted by the first byte of their prefix
+}
+
+// child returns the child whose prefix starts with b, or the position to
+// insert it at.
+func (n *radixNode) child(b byte) (int, *radixNode) {
+	i := sort.Search(len(n.children), func(i int) bool {
+		return n.children[i].prefix[0] >= b
+	})
+	if i < len(n.children) && n.children[i].prefix[0] == b {
+		return i, n.children[i]
+	}
+	return i, nil
+}
+
+func (t *radixTree) insert(key string) {
+	n := &t.root
+	for key != "" {
+		i, child := n.child(key[0])
+		if child == nil {
+			n.children = append(n.children, nil)
+			copy(n.children[i+1:], n.children[i:])
+			n.children[i] = &radixNode{prefix: key, leaf: true}
+			t.len++
+			return
+		}
+		common := commonPrefixLen(key, child.prefix)
+		if common < len(child.prefix) {
+			// split the edge where key diverges
+			split := &radixNode{prefix: child.prefix[:common], children: []*radixNode{child}}
+			child.prefix = child.prefix[common:]
+			n.children[i] = split
+			child = split
+		}
+		key = key[common:]
+		n = child
+	}
+	if !n.leaf {
+		n.leaf = true
+		t.len++
+	}
+}
+
+// delete removes key and reports whether it was present.
+func (t *radixTree) delete(key string) bool {
+	if !t.root.delete(key) {
+		return false
+	}
+	t.len--
+	return true
+}
+
+func (n *radixNode) delete(key string) bool {
+	if key == "" {
+		if !n.leaf {
+			return false
+		}
+		n.leaf = false
+		return true
+	}
+	i, child := n.child(key[0])
+	if child == nil || !strings.HasPrefix(key, child.prefix) {
+		return false
+	}
+	if !child.delete(key[len(child.prefix):]) {
+		return false
+	}
+	// keep the tree compressed
+	if !child.leaf {
+		switch len(child.children) {
+		case 0:
+			n.children = append(n.children[:i], n.children[i+1:]...)
+		case 1:
+			grandchild := child.children[0]
+			grandchild.prefix = child.prefix + grandchild.prefix
+			n.children[i] = grandchild
+		}
+	}
+	return true
+}
+
+// walkPrefix calls fn for the keys starting with prefix in lexical order
+// until it returns false.
+func (t *radixTree) walkPrefix(prefix string, fn func(key string) bool) {
+	n, path := &t.root, ""
+	for prefix != "" {
+		_, child := n.child(prefix[0])
+		if child == nil {
+			return
+		}
+		switch {
+		case strings.HasPrefix(prefix, child.prefix):
+			prefix = prefix[len(child.prefix):]
+		case strings.HasPrefix(child.prefix, prefix):
+			prefix = ""
+		default:
+			return
+		}
+		path += child.prefix
+		n = child
+	}
+	n.walk(path, fn)
+}
+
+func (n *radixNode) walk(path string, fn func(key string) bool) bool {
+	if n.leaf && !fn(path) {
+		return false
+	}
+	for _, child := range n.children {
+		if !child.walk(path+child.prefix, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+func commonPrefixLen(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
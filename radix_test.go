@@ -0,0 +1,69 @@
+package fastlocalcache
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func prefixKeys(tree *radixTree, prefix string) []string {
+	var keys []string
+	tree.walkPrefix(prefix, func(key string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func TestRadixTree(t *testing.T) {
+	var tree radixTree
+	for _, key := range []string{"user:1:name", "user:1:profile", "user:12", "user:2", "order:1", "", "user:1:name"} {
+		tree.insert(key)
+	}
+	assert.Equal(t, 6, tree.len)
+	assert.Equal(t, []string{"user:12", "user:1:name", "user:1:profile"}, prefixKeys(&tree, "user:1"))
+	assert.Equal(t, []string{"user:1:name", "user:1:profile"}, prefixKeys(&tree, "user:1:"))
+	assert.Equal(t, []string{"user:2"}, prefixKeys(&tree, "user:2"))
+	assert.Nil(t, prefixKeys(&tree, "user:3"))
+	assert.Nil(t, prefixKeys(&tree, "user:1:namex"))
+	assert.Len(t, prefixKeys(&tree, ""), 6)
+
+	assert.True(t, tree.delete("user:1:name"))
+	assert.False(t, tree.delete("user:1:name"))
+	assert.False(t, tree.delete("user:"))
+	assert.True(t, tree.delete(""))
+	assert.Equal(t, []string{"user:12", "user:1:profile"}, prefixKeys(&tree, "user:1"))
+	assert.Equal(t, 4, tree.len)
+}
+
+func TestRadixTreeRandom(t *testing.T) {
+	var tree radixTree
+	set := map[string]bool{}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("k:%d:%d", rnd.Intn(20), rnd.Intn(50))
+		if rnd.Intn(3) == 0 {
+			assert.Equal(t, set[key], tree.delete(key))
+			delete(set, key)
+		} else {
+			tree.insert(key)
+			set[key] = true
+		}
+	}
+	assert.Equal(t, len(set), tree.len)
+
+	for _, prefix := range []string{"", "k:1", "k:1:", "k:7:4", "x"} {
+		var want []string
+		for key := range set {
+			if strings.HasPrefix(key, prefix) {
+				want = append(want, key)
+			}
+		}
+		sort.Strings(want)
+		assert.Equal(t, want, prefixKeys(&tree, prefix))
+	}
+}
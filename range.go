@@ -0,0 +1,104 @@
+package fastlocalcache
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RangeFunc is called by Range for each live entry with its serialized value
+// and expiry, the zero time if it never expires. Returning false stops the
+// iteration. raw must not be modified.
+type RangeFunc func(key string, raw []byte, expireAt time.Time) bool
+
+// Range calls fn for each entry that has not expired, shard by shard. It does
+// not lock the shards, so entries written during the iteration may or may
+// not be visited, but each key is visited at most once.
+func (c *Cache) Range(fn RangeFunc) {
+	if c.isClosed() {
+		return
+	}
+	now := c.clock.Now().Unix()
+	for _, s := range c.shardedMap.shards {
+		if !c.rangeShard(s, now, fn) {
+			return
+		}
+	}
+}
+
+// RangeParallel is like Range but walks several shards at once, so fn must be
+// safe for concurrent use. Once fn returns false no further shard is started
+// and the running ones stop at their next entry. It returns when all
+// goroutines are done.
+func (c *Cache) RangeParallel(fn RangeFunc) {
+	if c.isClosed() {
+		return
+	}
+	now := c.clock.Now().Unix()
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(c.shardedMap.shards) {
+		workers = len(c.shardedMap.shards)
+	}
+
+	var next, stopped int32
+	stoppable := func(key string, raw []byte, expireAt time.Time) bool {
+		if atomic.LoadInt32(&stopped) == 1 || !fn(key, raw, expireAt) {
+			atomic.StoreInt32(&stopped, 1)
+			return false
+		}
+		return true
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&stopped) == 0 {
+				i := int(atomic.AddInt32(&next, 1)) - 1
+				if i >= len(c.shardedMap.shards) {
+					return
+				}
+				c.rangeShard(c.shardedMap.shards[i], now, stoppable)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Keys returns the keys that have not expired, in no particular order.
+func (c *Cache) Keys() []string {
+	keys := make([]string, 0, c.Len())
+	c.Range(func(key string, _ []byte, _ time.Time) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// rangeShard calls fn for the live entries of s and reports whether it was
+// not stopped.
+func (c *Cache) rangeShard(s *shard, now int64, fn RangeFunc) bool {
+	completed := true
+	s.items.Range(func(key, value any) bool {
+		completed = c.visit(key.(string), value.(*cacheItem), now, fn)
+		return completed
+	})
+	return completed
+}
+
+// visit calls fn for ci unless it has expired, and reports whether to go on.
+func (c *Cache) visit(key string, ci *cacheItem, now int64, fn RangeFunc) bool {
+	if hasExpired(now, ci.expireAt) {
+		return true
+	}
+	raw, err := c.itemBytes(ci)
+	if err != nil {
+		return true
+	}
+	var expireAt time.Time
+	if ci.expireAt != neverExpire {
+		expireAt = time.Unix(ci.expireAt, 0)
+	}
+	return fn(key, raw, expireAt)
+}
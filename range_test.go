@@ -0,0 +1,100 @@
+package fastlocalcache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheRange(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithClock(clock))
+	defer cache.Close()
+
+	ttl := time.Minute
+	assert.Nil(t, cache.Set("t-1", "a", nil))
+	assert.Nil(t, cache.Set("t-2", "b", &ttl))
+	assert.Nil(t, cache.Set("t-3", "c", &ttl))
+	_, err := cache.IncrBy("t-4", 7, nil)
+	assert.Nil(t, err)
+
+	got := map[string]string{}
+	cache.Range(func(key string, raw []byte, expireAt time.Time) bool {
+		got[key] = string(raw)
+		if key == "t-2" {
+			assert.Equal(t, clock.Now().Add(ttl).Unix(), expireAt.Unix())
+		}
+		if key == "t-1" {
+			assert.True(t, expireAt.IsZero())
+		}
+		return true
+	})
+	assert.Equal(t, map[string]string{"t-1": `"a"`, "t-2": `"b"`, "t-3": `"c"`, "t-4": "7"}, got)
+
+	// expired entries are skipped before the expirer removes them
+	clock.Add(ttl + time.Second)
+	keys := cache.Keys()
+	sort.Strings(keys)
+	assert.Equal(t, []string{"t-1", "t-4"}, keys)
+
+	// returning false stops the iteration
+	calls := 0
+	cache.Range(func(string, []byte, time.Time) bool {
+		calls++
+		return false
+	})
+	assert.Equal(t, 1, calls)
+}
+
+func TestCacheRangeParallel(t *testing.T) {
+	cache := NewCache()
+	defer cache.Close()
+
+	for i := 0; i < 1000; i++ {
+		assert.Nil(t, cache.Set(fmt.Sprintf("t-%d", i), i, nil))
+	}
+
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	cache.RangeParallel(func(key string, _ []byte, _ time.Time) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.False(t, seen[key])
+		seen[key] = true
+		return true
+	})
+	assert.Len(t, seen, 1000)
+
+	var calls int32
+	cache.RangeParallel(func(string, []byte, time.Time) bool {
+		return atomic.AddInt32(&calls, 1) < 10
+	})
+	assert.Less(t, atomic.LoadInt32(&calls), int32(1000))
+}
+
+func TestCacheRangeConcurrentWrites(t *testing.T) {
+	cache := NewCache(WithMaxEntries(500))
+	defer cache.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 5000; i++ {
+			assert.Nil(t, cache.Set(fmt.Sprintf("t-%d", i), i, nil))
+			if i%3 == 0 {
+				cache.Del(fmt.Sprintf("t-%d", i/2))
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		cache.Range(func(string, []byte, time.Time) bool { return true })
+		cache.RangeParallel(func(string, []byte, time.Time) bool { return true })
+	}
+	<-done
+	assert.Equal(t, cache.Len(), int64(len(cache.Keys())))
+}
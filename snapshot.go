@@ -0,0 +1,262 @@
+package fastlocalcache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// A snapshot is the magic bytes and the format version, the entries each
+// prefixed with snapshotEntry, snapshotEnd and the big endian CRC32 (IEEE)
+// of all the preceding bytes. An entry is the key, the serialized value, the
+// unix expireAt and the tags; strings and byte slices are prefixed with their
+// uvarint length, numbers are varints.
+const (
+	snapshotMagic   = "FLCS"
+	snapshotVersion = 1
+
+	snapshotEnd   byte = 0
+	snapshotEntry byte = 1
+)
+
+// SaveTo writes the entries that have not expired to w, see LoadFrom. It
+// iterates like Range, so entries written meanwhile may or may not be saved.
+func (c *Cache) SaveTo(w io.Writer) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	bw := bufio.NewWriter(w)
+	crc := crc32.NewIEEE()
+	sw := &snapshotWriter{w: io.MultiWriter(bw, crc)}
+	sw.writeString(snapshotMagic)
+	sw.writeByte(snapshotVersion)
+
+	now := c.clock.Now().Unix()
+	for _, s := range c.shardedMap.shards {
+		s.items.Range(func(key, value any) bool {
+			ci := value.(*cacheItem)
+			if hasExpired(now, ci.expireAt) {
+				return true
+			}
+			raw, err := c.itemBytes(ci)
+			if err != nil {
+				sw.err = err
+				return false
+			}
+			sw.writeByte(snapshotEntry)
+			sw.writeEntry(key.(string), raw, ci)
+			return sw.err == nil
+		})
+	}
+	sw.writeByte(snapshotEnd)
+	if sw.err != nil {
+		return sw.err
+	}
+	if err := binary.Write(bw, binary.BigEndian, crc.Sum32()); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// LoadFrom stores the entries of a snapshot written by SaveTo, with their
+// original expiry; the ones that expired since are skipped, as are the ones
+// too large for this cache. Nothing is stored unless the whole snapshot is
+// valid, otherwise ErrCorruptSnapshot is returned.
+func (c *Cache) LoadFrom(r io.Reader) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	br := bufio.NewReader(r)
+	cr := &crcReader{r: br}
+	sr := &snapshotReader{r: cr}
+	if magic := sr.readBytes(len(snapshotMagic)); sr.err == nil && string(magic) != snapshotMagic {
+		return fmt.Errorf("%w: bad magic bytes", ErrCorruptSnapshot)
+	}
+	if version := sr.readByte(); sr.err == nil && version != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrCorruptSnapshot, version)
+	}
+
+	type entry struct {
+		key string
+		ci  *cacheItem
+	}
+	var entries []entry
+	for {
+		marker := sr.readByte()
+		if sr.err != nil || marker == snapshotEnd {
+			break
+		}
+		if marker != snapshotEntry {
+			sr.err = fmt.Errorf("unknown record %d", marker)
+			break
+		}
+		key, ci := sr.readEntry()
+		entries = append(entries, entry{key, ci})
+	}
+	if sr.err != nil {
+		return fmt.Errorf("%w: %v", ErrCorruptSnapshot, sr.err)
+	}
+	// the checksum covers everything read so far and is not part of it
+	var sum uint32
+	if err := binary.Read(br, binary.BigEndian, &sum); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorruptSnapshot, err)
+	}
+	if sum != cr.crc {
+		return fmt.Errorf("%w: checksum mismatch", ErrCorruptSnapshot)
+	}
+
+	now := c.clock.Now()
+	for _, e := range entries {
+		if hasExpired(now.Unix(), e.ci.expireAt) {
+			continue
+		}
+		if c.refreshAfter > 0 {
+			e.ci.refreshAt = now.Add(c.refreshAfter).Unix()
+		}
+		err := c.shardedMap.Set(e.key, e.ci)
+		if err != nil && !errors.Is(err, ErrTooLarge) {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotWriter remembers the first error so that writes can be chained.
+type snapshotWriter struct {
+	w   io.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (w *snapshotWriter) write(p []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(p)
+	}
+}
+
+func (w *snapshotWriter) writeByte(b byte) {
+	w.write([]byte{b})
+}
+
+func (w *snapshotWriter) writeString(s string) {
+	w.write([]byte(s))
+}
+
+func (w *snapshotWriter) writeBytes(p []byte) {
+	w.writeUvarint(uint64(len(p)))
+	w.write(p)
+}
+
+func (w *snapshotWriter) writeUvarint(x uint64) {
+	w.write(w.buf[:binary.PutUvarint(w.buf[:], x)])
+}
+
+func (w *snapshotWriter) writeVarint(x int64) {
+	w.write(w.buf[:binary.PutVarint(w.buf[:], x)])
+}
+
+// writeEntry writes key and the serialized value raw of ci, with its expiry
+// and tags.
+func (w *snapshotWriter) writeEntry(key string, raw []byte, ci *cacheItem) {
+	w.writeBytes([]byte(key))
+	w.writeBytes(raw)
+	w.writeVarint(ci.expireAt)
+	w.writeUvarint(uint64(len(ci.tags)))
+	for _, tag := range ci.tags {
+		w.writeBytes([]byte(tag))
+	}
+}
+
+// snapshotReader remembers the first error so that reads can be chained, the
+// values read after it are zero.
+type snapshotReader struct {
+	r interface {
+		io.Reader
+		io.ByteReader
+	}
+	err error
+}
+
+// crcReader computes the CRC32 of the bytes consumed from r.
+type crcReader struct {
+	r   *bufio.Reader
+	crc uint32
+}
+
+func (r *crcReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.crc = crc32.Update(r.crc, crc32.IEEETable, p[:n])
+	return n, err
+}
+
+func (r *crcReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.crc = crc32.Update(r.crc, crc32.IEEETable, []byte{b})
+	}
+	return b, err
+}
+
+func (r *snapshotReader) readByte() byte {
+	if r.err != nil {
+		return 0
+	}
+	var b byte
+	b, r.err = r.r.ReadByte()
+	return b
+}
+
+// readLen reads a uvarint length.
+func (r *snapshotReader) readLen() int {
+	if r.err != nil {
+		return 0
+	}
+	var n uint64
+	n, r.err = binary.ReadUvarint(r.r)
+	if r.err == nil && n > math.MaxInt32 {
+		r.err = errors.New("length out of range")
+	}
+	return int(n)
+}
+
+// readBytes reads n bytes. The buffer grows with the data actually read, so
+// a corrupt length fails at the end of the input instead of allocating it.
+func (r *snapshotReader) readBytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r.r, int64(n)); err != nil {
+		r.err = err
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func (r *snapshotReader) readVarint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	var x int64
+	x, r.err = binary.ReadVarint(r.r)
+	return x
+}
+
+// readEntry reads what writeEntry wrote.
+func (r *snapshotReader) readEntry() (string, *cacheItem) {
+	key := string(r.readBytes(r.readLen()))
+	ci := &cacheItem{value: r.readBytes(r.readLen())}
+	ci.expireAt = r.readVarint()
+	if n := r.readLen(); n > 0 {
+		ci.tags = make([]string, 0, minInt(n, 64))
+		for i := 0; i < n && r.err == nil; i++ {
+			ci.tags = append(ci.tags, string(r.readBytes(r.readLen())))
+		}
+	}
+	return key, ci
+}
@@ -0,0 +1,80 @@
+package fastlocalcache
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheSnapshot(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithClock(clock))
+	defer cache.Close()
+
+	short, long := time.Minute, time.Hour
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, cache.Set(fmt.Sprintf("t-%d", i), i, nil))
+	}
+	assert.Nil(t, cache.Set("short", "s", &short))
+	assert.Nil(t, cache.SetWithTags("long", "l", &long, "tag"))
+	_, err := cache.IncrBy("counter", 7, nil)
+	assert.Nil(t, err)
+	clock.Add(time.Second)
+
+	var buf bytes.Buffer
+	assert.Nil(t, cache.SaveTo(&buf))
+
+	// entries expired during the downtime are skipped, the others keep
+	// their absolute expiry
+	clock.Add(short)
+	restored := NewCache(WithClock(clock))
+	defer restored.Close()
+	assert.Nil(t, restored.LoadFrom(bytes.NewReader(buf.Bytes())))
+	assert.Equal(t, int64(102), restored.Len())
+
+	var n int
+	assert.Nil(t, restored.Get("t-42", &n))
+	assert.Equal(t, 42, n)
+	assert.Nil(t, restored.Get("counter", &n))
+	assert.Equal(t, 7, n)
+	var str string
+	assert.ErrorIs(t, restored.Get("short", &str), ErrNotFound)
+	assert.Nil(t, restored.Get("long", &str))
+	assert.Equal(t, "l", str)
+	clock.Add(long)
+	assert.ErrorIs(t, restored.Get("long", &str), ErrExpired)
+	assert.Nil(t, restored.SetWithTags("long", "l", nil, "tag"))
+	assert.Equal(t, 1, restored.InvalidateTag("tag"))
+}
+
+func TestCacheSnapshotCorrupt(t *testing.T) {
+	cache := NewCache()
+	defer cache.Close()
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, cache.Set(fmt.Sprintf("t-%d", i), i, nil))
+	}
+	var buf bytes.Buffer
+	assert.Nil(t, cache.SaveTo(&buf))
+	snapshot := buf.Bytes()
+
+	restored := NewCache()
+	defer restored.Close()
+	// truncated
+	for _, n := range []int{0, 3, 5, len(snapshot) / 2, len(snapshot) - 1} {
+		assert.ErrorIs(t, restored.LoadFrom(bytes.NewReader(snapshot[:n])), ErrCorruptSnapshot)
+	}
+	// flipped bits
+	for _, i := range []int{0, 4, 10, len(snapshot) - 6, len(snapshot) - 1} {
+		corrupt := append([]byte(nil), snapshot...)
+		corrupt[i] ^= 0x40
+		assert.ErrorIs(t, restored.LoadFrom(bytes.NewReader(corrupt)), ErrCorruptSnapshot)
+	}
+	// nothing was stored from the corrupt snapshots
+	assert.Equal(t, int64(0), restored.Len())
+
+	assert.Nil(t, restored.LoadFrom(bytes.NewReader(snapshot)))
+	assert.Equal(t, int64(10), restored.Len())
+}
@@ -0,0 +1,65 @@
+package fastlocalcache
+
+import "sync/atomic"
+
+// Stats is a snapshot of the counters of a Cache.
+type Stats struct {
+	Hits         uint64 // reads that found a live entry
+	Misses       uint64 // reads that did not, ExpiredReads included
+	ExpiredReads uint64 // reads that found an expired entry
+	Sets         uint64 // writes, counter increments included
+	Deletes      uint64 // entries deleted by Del and the like
+	Evictions    uint64 // entries evicted by the eviction policy
+	Expirations  uint64 // expired entries removed by the background expirer
+}
+
+// HitRatio returns the share of reads that hit, 0 before the first read.
+func (s Stats) HitRatio() float64 {
+	reads := s.Hits + s.Misses
+	if reads == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(reads)
+}
+
+// Stats returns the counters summed over the shards. Each shard counts on
+// its own so that counting does not contend, as a result the snapshot is not
+// taken at a single instant.
+func (c *Cache) Stats() Stats {
+	var stats Stats
+	for _, s := range c.shardedMap.shards {
+		stats.Hits += atomic.LoadUint64(&s.stats.hits)
+		stats.Misses += atomic.LoadUint64(&s.stats.misses)
+		stats.ExpiredReads += atomic.LoadUint64(&s.stats.expiredReads)
+		stats.Sets += atomic.LoadUint64(&s.stats.sets)
+		stats.Deletes += atomic.LoadUint64(&s.stats.deletes)
+		stats.Evictions += atomic.LoadUint64(&s.stats.evictions)
+		stats.Expirations += atomic.LoadUint64(&s.stats.expirations)
+	}
+	return stats
+}
+
+// ResetStats sets the counters back to zero, operations running meanwhile
+// may be counted or not.
+func (c *Cache) ResetStats() {
+	for _, s := range c.shardedMap.shards {
+		atomic.StoreUint64(&s.stats.hits, 0)
+		atomic.StoreUint64(&s.stats.misses, 0)
+		atomic.StoreUint64(&s.stats.expiredReads, 0)
+		atomic.StoreUint64(&s.stats.sets, 0)
+		atomic.StoreUint64(&s.stats.deletes, 0)
+		atomic.StoreUint64(&s.stats.evictions, 0)
+		atomic.StoreUint64(&s.stats.expirations, 0)
+	}
+}
+
+// shardStats are the counters of a shard, see Stats.
+type shardStats struct {
+	hits         uint64
+	misses       uint64
+	expiredReads uint64
+	sets         uint64
+	deletes      uint64
+	evictions    uint64
+	expirations  uint64
+}
@@ -0,0 +1,56 @@
+package fastlocalcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheStats(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithClock(clock), WithMaxEntries(10), WithShards(1))
+	defer cache.Close()
+
+	assert.Equal(t, 0.0, cache.Stats().HitRatio())
+
+	ttl := time.Minute
+	for i := 0; i < 12; i++ {
+		assert.Nil(t, cache.Set(fmt.Sprintf("t-%d", i), i, nil))
+	}
+	assert.Nil(t, cache.Set("short", 0, &ttl))
+	assert.Nil(t, cache.Set("expiring", 0, &ttl))
+	_, err := cache.IncrBy("counter", 1, nil)
+	assert.Nil(t, err)
+	_, err = cache.IncrBy("counter", 1, nil)
+	assert.Nil(t, err)
+
+	var n int
+	assert.Nil(t, cache.Get("t-11", &n))
+	assert.Nil(t, cache.Get("counter", &n))
+	assert.Nil(t, cache.Get("short", &n))
+	assert.ErrorIs(t, cache.Get("t-0", &n), ErrNotFound)
+	cache.Del("t-11")
+	cache.Del("missing")
+
+	clock.Add(ttl + time.Second)
+	assert.ErrorIs(t, cache.Get("short", &n), ErrExpired)
+	clock.Add(time.Second)
+	cache.expire()
+
+	stats := cache.Stats()
+	assert.Equal(t, Stats{
+		Hits:         3,
+		Misses:       2,
+		ExpiredReads: 1,
+		Sets:         16,
+		Deletes:      1,
+		Evictions:    5,
+		Expirations:  1,
+	}, stats)
+	assert.InDelta(t, 0.6, stats.HitRatio(), 1e-9)
+
+	cache.ResetStats()
+	assert.Equal(t, Stats{}, cache.Stats())
+}
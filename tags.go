@@ -0,0 +1,93 @@
+package fastlocalcache
+
+import "time"
+
+// SetWithTags stores value under key like Set and tags it, so that
+// InvalidateTag of any of tags deletes it. Writing the key again without
+// tags drops them.
+func (c *Cache) SetWithTags(key string, value any, expiration *time.Duration, tags ...string) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	bs, err := c.serializer.Marshal(value)
+	if err != nil {
+		return &SerializationError{Op: "marshal", Err: err}
+	}
+	ci := c.newItem(bs, expiration)
+	ci.tags = uniqueTags(tags)
+	return c.shardedMap.Set(key, ci)
+}
+
+// uniqueTags returns a copy of tags without duplicates. The entry must not
+// share the slice of the caller, who could change it before the entry is
+// untagged.
+func uniqueTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	unique := make([]string, 0, len(tags))
+	for i, tag := range tags {
+		if !containsString(tags[:i], tag) {
+			unique = append(unique, tag)
+		}
+	}
+	return unique
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// InvalidateTag deletes every entry tagged with tag and returns how many it
+// deleted. All shards are locked for the duration, so no write interleaves
+// with it.
+func (c *Cache) InvalidateTag(tag string) int {
+	if c.isClosed() {
+		return 0
+	}
+	for _, s := range c.shardedMap.shards {
+		s.mu.Lock()
+	}
+	deleted := 0
+	for _, s := range c.shardedMap.shards {
+		for key := range s.tags[tag] {
+			s.delLocked(key)
+			deleted++
+		}
+	}
+	for _, s := range c.shardedMap.shards {
+		s.mu.Unlock()
+	}
+	return deleted
+}
+
+// tagLocked adds key to the index of the tags of ci.
+func (s *shard) tagLocked(key string, ci *cacheItem) {
+	for _, tag := range ci.tags {
+		if s.tags == nil {
+			s.tags = make(map[string]map[string]struct{})
+		}
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			s.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// untagLocked removes key from the index of the tags of ci.
+func (s *shard) untagLocked(key string, ci *cacheItem) {
+	for _, tag := range ci.tags {
+		keys := s.tags[tag]
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
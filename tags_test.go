@@ -0,0 +1,91 @@
+package fastlocalcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheInvalidateTag(t *testing.T) {
+	cache := NewCache()
+	defer cache.Close()
+
+	assert.Nil(t, cache.SetWithTags("product:42", "p", nil, "product:42", "tenant:acme"))
+	assert.Nil(t, cache.SetWithTags("price:42", 10, nil, "product:42"))
+	assert.Nil(t, cache.SetWithTags("product:43", "q", nil, "tenant:acme"))
+	assert.Nil(t, cache.Set("other", "o", nil))
+
+	assert.Equal(t, 2, cache.InvalidateTag("product:42"))
+	var str string
+	assert.ErrorIs(t, cache.Get("product:42", &str), ErrNotFound)
+	assert.ErrorIs(t, cache.Get("price:42", &str), ErrNotFound)
+	assert.Nil(t, cache.Get("product:43", &str))
+
+	// a write without tags drops them
+	assert.Nil(t, cache.Set("product:43", "r", nil))
+	assert.Equal(t, 0, cache.InvalidateTag("tenant:acme"))
+	assert.Nil(t, cache.Get("product:43", &str))
+	assert.Equal(t, 0, cache.InvalidateTag("missing"))
+	assert.Equal(t, int64(2), cache.Len())
+}
+
+func TestCacheTagIndexConsistency(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithClock(clock), WithMaxEntries(100), WithShards(4))
+	defer cache.Close()
+
+	ttl := time.Second
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("t-%d", i%300)
+		tag := fmt.Sprintf("tag-%d", i%7)
+		switch i % 4 {
+		case 0:
+			cache.Del(key)
+		case 1:
+			assert.Nil(t, cache.SetWithTags(key, i, &ttl, tag))
+		default:
+			assert.Nil(t, cache.SetWithTags(key, i, nil, tag, "all"))
+		}
+	}
+	clock.Add(3 * time.Second)
+	cache.expire()
+
+	// deleted, overwritten, expired and evicted keys left the index
+	for _, s := range cache.shardedMap.shards {
+		assert.Equal(t, int(s.len), len(s.tags["all"]))
+		for tag, keys := range s.tags {
+			for key := range keys {
+				value, ok := s.items.Load(key)
+				assert.True(t, ok)
+				assert.Contains(t, value.(*cacheItem).tags, tag)
+			}
+		}
+	}
+	assert.Equal(t, int(cache.Len()), cache.InvalidateTag("all"))
+	assert.Equal(t, int64(0), cache.Len())
+}
+
+func TestCacheTagsCopied(t *testing.T) {
+	cache := NewCache(WithMaxBytes(1024 * 1024))
+	defer cache.Close()
+
+	// changing the slice of the caller does not change the tags of the entry
+	tags := []string{"a", "b", "a"}
+	assert.Nil(t, cache.SetWithTags("t-1", 1, nil, tags...))
+	tags[0] = "z"
+	cache.Del("t-1")
+	for _, s := range cache.shardedMap.shards {
+		assert.Empty(t, s.tags)
+	}
+	assert.Equal(t, int64(0), cache.Bytes())
+
+	// duplicates are counted once
+	assert.Nil(t, cache.SetWithTags("t-2", 1, nil, "a", "a", "b"))
+	once := cache.Bytes()
+	cache.Del("t-2")
+	assert.Nil(t, cache.SetWithTags("t-2", 1, nil, "a", "b"))
+	assert.Equal(t, once, cache.Bytes())
+	assert.Equal(t, 1, cache.InvalidateTag("a"))
+}
@@ -0,0 +1,122 @@
+package fastlocalcache
+
+const (
+	wheelBits   = 6
+	wheelSlots  = 1 << wheelBits
+	wheelMask   = wheelSlots - 1
+	wheelLevels = 4
+	// wheelSpan is the furthest a timer can be placed, later timers are
+	// parked in the last slot reachable and placed again when cascaded.
+	wheelSpan = int64(1) << (wheelBits * wheelLevels)
+)
+
+// timer schedules the expiration of value, it is linked into a bucket of the
+// timing wheel until it fires or is cancelled.
+type timer[T any] struct {
+	value      T
+	when       int64 // unix second at which the value is expired
+	prev, next *timer[T]
+	bucket     *timer[T] // sentinel of the bucket holding the timer
+}
+
+// timingWheel is a hierarchical timing wheel with one second ticks. Level l
+// has 64 slots of 64^l seconds each, timers are placed on the lowest level
+// whose range covers them and cascade down as time advances, so advancing
+// costs O(1) per second plus O(1) per timer moved or fired, regardless of how
+// many timers are pending. It is not safe for concurrent use, each shard
+// owns a wheel and uses it with its lock held.
+type timingWheel[T any] struct {
+	current int64 // last second processed
+	levels  [wheelLevels][wheelSlots]timer[T]
+	due     timer[T] // timers scheduled at or before current
+}
+
+func newTimingWheel[T any](now int64) *timingWheel[T] {
+	w := &timingWheel[T]{current: now}
+	for l := range w.levels {
+		for s := range w.levels[l] {
+			initBucket(&w.levels[l][s])
+		}
+	}
+	initBucket(&w.due)
+	return w
+}
+
+func initBucket[T any](b *timer[T]) {
+	b.prev, b.next = b, b
+}
+
+// schedule returns the timer expiring value at the end of second expireAt.
+func (w *timingWheel[T]) schedule(value T, expireAt int64) *timer[T] {
+	t := &timer[T]{value: value, when: expireAt + 1}
+	w.addLocked(t)
+	return t
+}
+
+func (w *timingWheel[T]) cancel(t *timer[T]) {
+	if t.bucket != nil {
+		unlink(t)
+	}
+}
+
+func (w *timingWheel[T]) addLocked(t *timer[T]) {
+	delta := t.when - w.current
+	if delta <= 0 {
+		link(&w.due, t)
+		return
+	}
+	when := t.when
+	if delta >= wheelSpan {
+		when = w.current + wheelSpan - 1
+		delta = wheelSpan - 1
+	}
+	level := 0
+	for delta >= int64(1)<<(wheelBits*(level+1)) {
+		level++
+	}
+	slot := (when >> (wheelBits * level)) & wheelMask
+	link(&w.levels[level][slot], t)
+}
+
+// advance moves the wheel to now and returns the timers that fired.
+func (w *timingWheel[T]) advance(now int64) []*timer[T] {
+	var fired []*timer[T]
+	for w.current < now {
+		w.current++
+		// cascade the higher levels whose slot starts now
+		for level := 1; level < wheelLevels; level++ {
+			if w.current&(int64(1)<<(wheelBits*level)-1) != 0 {
+				break
+			}
+			bucket := &w.levels[level][(w.current>>(wheelBits*level))&wheelMask]
+			for t := bucket.next; t != bucket; t = bucket.next {
+				unlink(t)
+				w.addLocked(t)
+			}
+		}
+		fired = drain(&w.levels[0][w.current&wheelMask], fired)
+	}
+	return drain(&w.due, fired)
+}
+
+func drain[T any](bucket *timer[T], fired []*timer[T]) []*timer[T] {
+	for t := bucket.next; t != bucket; t = bucket.next {
+		unlink(t)
+		fired = append(fired, t)
+	}
+	return fired
+}
+
+func link[T any](bucket, t *timer[T]) {
+	t.bucket = bucket
+	t.prev = bucket.prev
+	t.next = bucket
+	bucket.prev.next = t
+	bucket.prev = t
+}
+
+func unlink[T any](t *timer[T]) {
+	t.prev.next = t.next
+	t.next.prev = t.prev
+	t.prev, t.next, t.bucket = nil, nil, nil
+}
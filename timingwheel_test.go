@@ -0,0 +1,54 @@
+package fastlocalcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimingWheel(t *testing.T) {
+	const start = int64(1_000_000)
+	w := newTimingWheel[int64](start)
+	delays := []int64{0, 1, 2, 63, 64, 65, 100, 4095, 4096, 5000, 300000, wheelSpan + 10}
+	timers := map[*timer[int64]]int64{}
+	for _, d := range delays {
+		tm := w.schedule(d, start+d)
+		timers[tm] = start + d + 1
+	}
+	cancelled := w.schedule(70, start+70)
+	w.cancel(cancelled)
+
+	// advance in uneven steps and check that every timer fires on time
+	now := start
+	for step := int64(1); len(timers) > 0; step = step*2 + 1 {
+		now += step
+		for _, tm := range w.advance(now) {
+			when, ok := timers[tm]
+			assert.True(t, ok)
+			assert.LessOrEqual(t, when, now)
+			assert.Greater(t, when, now-step)
+			delete(timers, tm)
+		}
+	}
+	assert.Nil(t, cancelled.bucket)
+}
+
+func TestCacheExpire(t *testing.T) {
+	cache := NewCache()
+	past := -2 * time.Second
+	future := time.Hour
+	assert.Nil(t, cache.Set("t-1", 1, &past))
+	assert.Nil(t, cache.Set("t-2", 2, &future))
+	assert.Nil(t, cache.Set("t-3", 3, nil))
+	// overwriting cancels the timer of the previous value
+	assert.Nil(t, cache.Set("t-4", 4, &past))
+	assert.Nil(t, cache.Set("t-4", 4, nil))
+
+	cache.shardedMap.expire(time.Now().Unix() + 1)
+	assert.Equal(t, int64(3), cache.Len())
+	cache.shardedMap.expire(time.Now().Add(future).Unix() + 2)
+	assert.Equal(t, int64(2), cache.Len())
+	var v int
+	assert.Nil(t, cache.Get("t-4", &v))
+}
@@ -0,0 +1,208 @@
+package fastlocalcache
+
+import (
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TypedCache stores values of type V as they are, without serialization, so
+// Get is free of allocations and returns the stored value itself rather than
+// a copy. It shares the sharding and the expiration of Cache but does not
+// limit its size: the serializer, hash, capacity and policy options are
+// ignored.
+type TypedCache[K comparable, V any] struct {
+	shards []*typedShard[K, V]
+	seed   maphash.Seed
+	clock  Clock
+	closed int32
+	done   chan struct{}
+}
+
+type typedItem[K comparable, V any] struct {
+	value    V
+	expireAt int64 // unix timestamp, in seconds
+	timer    *timer[typedExpiring[K, V]]
+}
+
+type typedExpiring[K comparable, V any] struct {
+	key  K
+	item *typedItem[K, V]
+}
+
+func NewTypedCache[K comparable, V any](opts ...Option) *TypedCache[K, V] {
+	var config Config
+	for _, opt := range opts {
+		opt(&config)
+	}
+	config = config.withDefaults()
+	c := &TypedCache[K, V]{
+		shards: make([]*typedShard[K, V], config.Shards),
+		seed:   maphash.MakeSeed(),
+		clock:  config.Clock,
+		done:   make(chan struct{}),
+	}
+	for i := range c.shards {
+		c.shards[i] = &typedShard[K, V]{clock: config.Clock}
+	}
+	go runExpirer(config.Context, config.CleanupInterval, c.done, c.expire, c.Close)
+	return c
+}
+
+func (c *TypedCache[K, V]) getShard(key K) *typedShard[K, V] {
+	hash := maphash.Comparable(c.seed, key)
+	return c.shards[hash%uint64(len(c.shards))]
+}
+
+func (c *TypedCache[K, V]) expire() {
+	now := c.clock.Now().Unix()
+	for _, s := range c.shards {
+		s.expire(now)
+	}
+}
+
+// Get returns the value of key, ok is false if the key is missing, expired
+// or the cache is closed.
+func (c *TypedCache[K, V]) Get(key K) (value V, ok bool) {
+	if c.isClosed() {
+		return value, false
+	}
+	s := c.getShard(key)
+	item, ok := s.get(key)
+	if !ok {
+		return value, false
+	}
+	if hasExpired(c.clock.Now().Unix(), item.expireAt) {
+		s.delIfSame(key, item)
+		return value, false
+	}
+	return item.value, true
+}
+
+func (c *TypedCache[K, V]) Set(key K, value V, expiration *time.Duration) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	item := &typedItem[K, V]{value: value, expireAt: neverExpire}
+	if expiration != nil {
+		item.expireAt = c.clock.Now().Add(*expiration).Unix()
+	}
+	c.getShard(key).set(key, item)
+	return nil
+}
+
+func (c *TypedCache[K, V]) Del(key K) {
+	if c.isClosed() {
+		return
+	}
+	c.getShard(key).del(key)
+}
+
+// Len returns the number of entries, including the expired ones not removed
+// yet.
+func (c *TypedCache[K, V]) Len() int64 {
+	var n int64
+	for _, s := range c.shards {
+		n += atomic.LoadInt64(&s.len)
+	}
+	return n
+}
+
+// Close stops the background expirer and drops all entries, see Cache.Close.
+func (c *TypedCache[K, V]) Close() error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return nil
+	}
+	close(c.done)
+	for _, s := range c.shards {
+		s.clear()
+	}
+	return nil
+}
+
+func (c *TypedCache[K, V]) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
+
+// typedShard mirrors shard: reads are lock free, writes are serialized by mu.
+type typedShard[K comparable, V any] struct {
+	mu    sync.Mutex
+	items sync.Map
+	len   int64
+	wheel *timingWheel[typedExpiring[K, V]] // created by the first write with an expiry
+	clock Clock
+}
+
+func (s *typedShard[K, V]) get(key K) (*typedItem[K, V], bool) {
+	value, ok := s.items.Load(key)
+	if !ok {
+		return nil, false
+	}
+	return value.(*typedItem[K, V]), true
+}
+
+func (s *typedShard[K, V]) set(key K, item *typedItem[K, V]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if item.expireAt != neverExpire {
+		if s.wheel == nil {
+			s.wheel = newTimingWheel[typedExpiring[K, V]](s.clock.Now().Unix())
+		}
+		item.timer = s.wheel.schedule(typedExpiring[K, V]{key, item}, item.expireAt)
+	}
+	old, loaded := s.items.Swap(key, item)
+	if !loaded {
+		atomic.AddInt64(&s.len, 1)
+		return
+	}
+	if oldItem := old.(*typedItem[K, V]); oldItem.timer != nil {
+		s.wheel.cancel(oldItem.timer)
+	}
+}
+
+func (s *typedShard[K, V]) del(key K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, loaded := s.items.LoadAndDelete(key); loaded {
+		s.removedLocked(old.(*typedItem[K, V]))
+	}
+}
+
+func (s *typedShard[K, V]) expire(now int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.wheel == nil {
+		return
+	}
+	for _, t := range s.wheel.advance(now) {
+		if s.items.CompareAndDelete(t.value.key, t.value.item) {
+			s.removedLocked(t.value.item)
+		}
+	}
+}
+
+func (s *typedShard[K, V]) delIfSame(key K, item *typedItem[K, V]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.items.CompareAndDelete(key, item) {
+		s.removedLocked(item)
+	}
+}
+
+func (s *typedShard[K, V]) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items.Range(func(key, value any) bool {
+		s.items.Delete(key)
+		s.removedLocked(value.(*typedItem[K, V]))
+		return true
+	})
+}
+
+func (s *typedShard[K, V]) removedLocked(item *typedItem[K, V]) {
+	atomic.AddInt64(&s.len, -1)
+	if item.timer != nil {
+		s.wheel.cancel(item.timer)
+	}
+}
@@ -0,0 +1,70 @@
+package fastlocalcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type typedValue struct {
+	Name  string
+	Count int
+}
+
+func TestTypedCache(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewTypedCache[string, *typedValue](WithClock(clock))
+	defer cache.Close()
+
+	v := &typedValue{Name: "a", Count: 1}
+	assert.Nil(t, cache.Set("t-1", v, nil))
+	assert.Nil(t, cache.Set("t-1", v, nil))
+	assert.Equal(t, int64(1), cache.Len())
+
+	// the stored value itself is returned
+	got, ok := cache.Get("t-1")
+	assert.True(t, ok)
+	assert.Same(t, v, got)
+
+	cache.Del("t-1")
+	_, ok = cache.Get("t-1")
+	assert.False(t, ok)
+	assert.Equal(t, int64(0), cache.Len())
+
+	// expiration
+	ttl := time.Minute
+	assert.Nil(t, cache.Set("t-2", v, &ttl))
+	clock.Add(ttl + time.Second)
+	_, ok = cache.Get("t-2")
+	assert.False(t, ok)
+	assert.Equal(t, int64(0), cache.Len())
+
+	assert.Nil(t, cache.Set("t-3", v, &ttl))
+	clock.Add(ttl + time.Second)
+	cache.expire()
+	assert.Equal(t, int64(0), cache.Len())
+
+	assert.Nil(t, cache.Close())
+	_, ok = cache.Get("t-1")
+	assert.False(t, ok)
+	assert.ErrorIs(t, cache.Set("t-1", v, nil), ErrClosed)
+}
+
+func TestTypedCacheGetAllocs(t *testing.T) {
+	ints := NewTypedCache[int, typedValue]()
+	defer ints.Close()
+	assert.Nil(t, ints.Set(1, typedValue{Name: "a"}, nil))
+	allocs := testing.AllocsPerRun(100, func() {
+		ints.Get(1)
+	})
+	assert.Equal(t, float64(0), allocs)
+
+	strs := NewTypedCache[string, int]()
+	defer strs.Close()
+	assert.Nil(t, strs.Set("t-1", 1, nil))
+	allocs = testing.AllocsPerRun(100, func() {
+		strs.Get("t-1")
+	})
+	assert.Equal(t, float64(0), allocs)
+}
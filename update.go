@@ -0,0 +1,46 @@
+package fastlocalcache
+
+import "time"
+
+// UpdateFunc computes the new serialized value of a key from the old one,
+// exists is false if the key is missing or expired. It returns the value to
+// store with its expiration, nil meaning never, or del to delete the key. old
+// is the stored value, read concurrently without locking, so it must not be
+// modified.
+type UpdateFunc func(old []byte, exists bool) (value []byte, expiration *time.Duration, del bool)
+
+// Update runs fn and applies its result atomically: no other write of key
+// happens in between. fn runs with the shard of key locked, so it must be
+// quick and must not call the cache. The key keeps its tags, see SetWithTags.
+// It returns ErrTooLarge if the new value does not fit.
+func (c *Cache) Update(key string, fn UpdateFunc) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	s := c.shardedMap.getShard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var old []byte
+	var tags []string
+	exists := false
+	if value, ok := s.items.Load(key); ok {
+		ci := value.(*cacheItem)
+		if !hasExpired(c.clock.Now().Unix(), ci.expireAt) {
+			bs, err := c.itemBytes(ci)
+			if err != nil {
+				return err
+			}
+			old, tags, exists = bs, ci.tags, true
+		}
+	}
+
+	bs, expiration, del := fn(old, exists)
+	if del {
+		s.delLocked(key)
+		return nil
+	}
+	ci := c.newItem(bs, expiration)
+	ci.tags = tags
+	return s.setLocked(key, ci)
+}
@@ -0,0 +1,64 @@
+package fastlocalcache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheUpdate(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithClock(clock))
+	defer cache.Close()
+
+	incr := func(old []byte, exists bool) ([]byte, *time.Duration, bool) {
+		n := 0
+		if exists {
+			n, _ = strconv.Atoi(string(old))
+		}
+		return []byte(strconv.Itoa(n + 1)), nil, false
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				assert.Nil(t, cache.Update("counter", incr))
+			}
+		}()
+	}
+	wg.Wait()
+	var n int
+	assert.Nil(t, cache.Get("counter", &n))
+	assert.Equal(t, 800, n)
+
+	// expired values are reported as missing
+	ttl := time.Minute
+	assert.Nil(t, cache.Set("t-1", 5, &ttl))
+	clock.Add(ttl + time.Second)
+	assert.Nil(t, cache.Update("t-1", func(old []byte, exists bool) ([]byte, *time.Duration, bool) {
+		assert.False(t, exists)
+		assert.Nil(t, old)
+		return []byte("1"), &ttl, false
+	}))
+	assert.Nil(t, cache.Get("t-1", &n))
+	assert.Equal(t, 1, n)
+
+	// del removes the key
+	assert.Nil(t, cache.Update("t-1", func(old []byte, exists bool) ([]byte, *time.Duration, bool) {
+		assert.True(t, exists)
+		assert.Equal(t, []byte("1"), old)
+		return nil, nil, true
+	}))
+	assert.ErrorIs(t, cache.Get("t-1", &n), ErrNotFound)
+	// the key keeps its tags
+	assert.Nil(t, cache.SetWithTags("t-2", 1, nil, "tag"))
+	assert.Nil(t, cache.Update("t-2", incr))
+	assert.Equal(t, 1, cache.InvalidateTag("tag"))
+	assert.ErrorIs(t, cache.Get("t-2", &n), ErrNotFound)
+}
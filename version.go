@@ -0,0 +1,65 @@
+package fastlocalcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// GetWithVersion gets the value of key like Get and returns its version. The
+// version changes with every write of the key and is never reused, even
+// after the key is deleted.
+func (c *Cache) GetWithVersion(key string, value any) (uint64, error) {
+	if c.isClosed() {
+		return 0, ErrClosed
+	}
+	ci, err := c.getItem(key, c.clock.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	// counters are incremented in place, reading the version first makes an
+	// increment landing before the count is read fail CompareAndSet instead
+	// of being overwritten
+	version := atomic.LoadUint64(&ci.version)
+	if err := c.unmarshalItem(ci, value); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// CompareAndSet stores value under key only if the current version of key is
+// expectedVersion, 0 meaning that the key is missing or expired. It returns
+// the new version, or ErrVersionMismatch if the key was written in between.
+func (c *Cache) CompareAndSet(key string, value any, expectedVersion uint64, expiration *time.Duration) (uint64, error) {
+	if c.isClosed() {
+		return 0, ErrClosed
+	}
+	bs, err := c.serializer.Marshal(value)
+	if err != nil {
+		return 0, &SerializationError{Op: "marshal", Err: err}
+	}
+	ci := c.newItem(bs, expiration)
+	s := c.shardedMap.getShard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.liveVersionLocked(key, c.clock.Now().Unix()) != expectedVersion {
+		return 0, ErrVersionMismatch
+	}
+	if err := s.setLocked(key, ci); err != nil {
+		return 0, err
+	}
+	return ci.version, nil
+}
+
+// liveVersionLocked returns the version of key, 0 if it is missing or
+// expired.
+func (s *shard) liveVersionLocked(key string, now int64) uint64 {
+	value, ok := s.items.Load(key)
+	if !ok {
+		return 0
+	}
+	ci := value.(*cacheItem)
+	if hasExpired(now, ci.expireAt) {
+		return 0
+	}
+	return ci.version
+}
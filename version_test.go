@@ -0,0 +1,119 @@
+package fastlocalcache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheCompareAndSet(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithClock(clock))
+	defer cache.Close()
+
+	// version 0 creates a missing key
+	v1, err := cache.CompareAndSet("t-1", "a", 0, nil)
+	assert.Nil(t, err)
+	assert.NotZero(t, v1)
+	_, err = cache.CompareAndSet("t-1", "b", 0, nil)
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+
+	var str string
+	version, err := cache.GetWithVersion("t-1", &str)
+	assert.Nil(t, err)
+	assert.Equal(t, v1, version)
+	assert.Equal(t, "a", str)
+
+	v2, err := cache.CompareAndSet("t-1", "b", v1, nil)
+	assert.Nil(t, err)
+	assert.Greater(t, v2, v1)
+	_, err = cache.CompareAndSet("t-1", "c", v1, nil)
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+
+	// any write bumps the version
+	assert.Nil(t, cache.Set("t-1", "d", nil))
+	_, err = cache.CompareAndSet("t-1", "e", v2, nil)
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+
+	// versions are not reused after a delete, expired keys count as missing
+	cache.Del("t-1")
+	ttl := time.Minute
+	v3, err := cache.CompareAndSet("t-1", "f", 0, &ttl)
+	assert.Nil(t, err)
+	assert.Greater(t, v3, v2)
+	clock.Add(ttl + time.Second)
+	_, err = cache.CompareAndSet("t-1", "g", 0, nil)
+	assert.Nil(t, err)
+}
+
+func TestCacheCompareAndSetConcurrent(t *testing.T) {
+	cache := NewCache()
+	defer cache.Close()
+	assert.Nil(t, cache.Set("counter", 0, nil))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				for {
+					var n int
+					version, err := cache.GetWithVersion("counter", &n)
+					assert.Nil(t, err)
+					_, err = cache.CompareAndSet("counter", n+1, version, nil)
+					if !errors.Is(err, ErrVersionMismatch) {
+						assert.Nil(t, err)
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var n int
+	assert.Nil(t, cache.Get("counter", &n))
+	assert.Equal(t, 800, n)
+}
+
+func TestCacheCompareAndSetIncrBy(t *testing.T) {
+	cache := NewCache()
+	defer cache.Close()
+	_, err := cache.IncrBy("counter", 0, nil)
+	assert.Nil(t, err)
+
+	// increments interleaved with read-modify-writes are not lost
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if g%2 == 0 {
+					_, err := cache.IncrBy("counter", 1, nil)
+					assert.Nil(t, err)
+					continue
+				}
+				for {
+					var n int64
+					version, err := cache.GetWithVersion("counter", &n)
+					assert.Nil(t, err)
+					_, err = cache.CompareAndSet("counter", n+1, version, nil)
+					if !errors.Is(err, ErrVersionMismatch) {
+						assert.Nil(t, err)
+						break
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	var n int64
+	assert.Nil(t, cache.Get("counter", &n))
+	assert.Equal(t, int64(1600), n)
+}